@@ -9,3 +9,8 @@ func Format(t time.Time, pattern string) string {
 	return t.Format("15:04:05")
 }
 
+// Append appends the formatted time to b, like Format but without
+// allocating.
+func Append(b []byte, t time.Time, pattern string) []byte {
+	return t.AppendFormat(b, "15:04:05")
+}
@@ -13,7 +13,9 @@ func TestFormat(t *testing.T) {
 	}
 }
 
-
-
-
-
+func TestAppend(t *testing.T) {
+	now := time.Now()
+	if got, want := string(Append([]byte("at "), now, "")), "at "+Format(now, ""); got != want {
+		t.Errorf("Append = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,417 @@
+// Package yamlite parses the subset of YAML used by logging config files.
+//
+// Supported: block mappings and sequences (indented with spaces), sequences
+// of mappings ("- key: value"), single-line flow collections ([a, b] and
+// {k: v}), plain, single- and double-quoted scalars, comments and a leading
+// "---". Not supported: anchors and aliases, tags, multi-line scalars (| and
+// >), multi-document streams and flow collections spanning lines.
+//
+// Parse returns the same shapes encoding/json produces for interface{}:
+// map[string]interface{}, []interface{}, string, int64, float64, bool and nil,
+// so a document can be re-encoded as JSON and decoded into a struct.
+package yamlite
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// line is one non-blank, comment-stripped source line.
+type line struct {
+	num    int // 1-based source line number
+	indent int
+	text   string
+}
+
+// parser walks the lines of one document.
+type parser struct {
+	lines []line
+	pos   int
+}
+
+// Parse parses a YAML-subset document. An empty document yields nil.
+func Parse(data []byte) (interface{}, error) {
+	lines, err := splitLines(string(data))
+	if err != nil {
+		return nil, err
+	}
+	if len(lines) == 0 {
+		return nil, nil
+	}
+	p := &parser{lines: lines}
+	v, err := p.block(lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.lines) {
+		return nil, p.errorf(p.lines[p.pos], "unexpected indentation")
+	}
+	return v, nil
+}
+
+// splitLines strips comments and blank lines and measures indentation.
+func splitLines(src string) ([]line, error) {
+	var out []line
+	for i, raw := range strings.Split(src, "\n") {
+		raw = strings.TrimRight(raw, " \t\r")
+		text := strings.TrimLeft(raw, " ")
+		if strings.HasPrefix(text, "\t") {
+			return nil, fmt.Errorf("line %d: tabs are not allowed for indentation", i+1)
+		}
+		text = strings.TrimRight(stripComment(text), " \t")
+		if text == "" || (len(out) == 0 && text == "---") {
+			continue
+		}
+		if text == "---" || text == "..." {
+			return nil, fmt.Errorf("line %d: multiple documents are not supported", i+1)
+		}
+		out = append(out, line{num: i + 1, indent: len(raw) - len(strings.TrimLeft(raw, " ")), text: text})
+	}
+	return out, nil
+}
+
+// stripComment removes a "#" comment that starts the text or follows
+// whitespace, outside quotes.
+func stripComment(s string) string {
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || s[i-1] == ' ' || s[i-1] == '\t'):
+			return s[:i]
+		}
+	}
+	return s
+}
+
+func (p *parser) errorf(l line, format string, args ...interface{}) error {
+	return fmt.Errorf("line %d: %s", l.num, fmt.Sprintf(format, args...))
+}
+
+// block parses the mapping or sequence whose lines start at indent.
+func (p *parser) block(indent int) (interface{}, error) {
+	if isSeqItem(p.lines[p.pos].text) {
+		return p.sequence(indent)
+	}
+	return p.mapping(indent)
+}
+
+func isSeqItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// mapping parses "key: value" lines at indent.
+func (p *parser) mapping(indent int) (interface{}, error) {
+	m := map[string]interface{}{}
+	for p.pos < len(p.lines) {
+		l := p.lines[p.pos]
+		if l.indent < indent {
+			break
+		}
+		if l.indent > indent {
+			return nil, p.errorf(l, "unexpected indentation")
+		}
+		if isSeqItem(l.text) {
+			return nil, p.errorf(l, "sequence item where a mapping key was expected")
+		}
+		key, rest, ok, err := splitKey(l.text)
+		if err != nil {
+			return nil, p.errorf(l, "%v", err)
+		}
+		if !ok {
+			return nil, p.errorf(l, "expected \"key: value\", got %q", l.text)
+		}
+		if _, dup := m[key]; dup {
+			return nil, p.errorf(l, "duplicate key %q", key)
+		}
+		p.pos++
+		var v interface{}
+		if rest != "" {
+			v, err = parseInline(rest)
+			if err != nil {
+				return nil, p.errorf(l, "%v", err)
+			}
+		} else if p.pos < len(p.lines) {
+			next := p.lines[p.pos]
+			switch {
+			case next.indent > indent:
+				v, err = p.block(next.indent)
+			case next.indent == indent && isSeqItem(next.text):
+				// A sequence may sit at the same indentation as its key.
+				v, err = p.sequence(indent)
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+		m[key] = v
+	}
+	return m, nil
+}
+
+// sequence parses "- item" lines at indent.
+func (p *parser) sequence(indent int) (interface{}, error) {
+	s := []interface{}{}
+	for p.pos < len(p.lines) {
+		l := p.lines[p.pos]
+		if l.indent < indent || !isSeqItem(l.text) {
+			if l.indent > indent {
+				return nil, p.errorf(l, "unexpected indentation")
+			}
+			break
+		}
+		if l.indent > indent {
+			return nil, p.errorf(l, "unexpected indentation")
+		}
+		rest := strings.TrimLeft(strings.TrimPrefix(l.text, "-"), " ")
+		if rest == "" {
+			p.pos++
+			var v interface{}
+			if p.pos < len(p.lines) && p.lines[p.pos].indent > indent {
+				var err error
+				if v, err = p.block(p.lines[p.pos].indent); err != nil {
+					return nil, err
+				}
+			}
+			s = append(s, v)
+			continue
+		}
+		if _, _, isKey, _ := splitKey(rest); isKey || isSeqItem(rest) {
+			// "- key: value" starts a nested block whose first line is the
+			// rest of this one; its siblings are indented to match.
+			p.lines[p.pos] = line{num: l.num, indent: l.indent + len(l.text) - len(rest), text: rest}
+			v, err := p.block(p.lines[p.pos].indent)
+			if err != nil {
+				return nil, err
+			}
+			s = append(s, v)
+			continue
+		}
+		v, err := parseInline(rest)
+		if err != nil {
+			return nil, p.errorf(l, "%v", err)
+		}
+		s = append(s, v)
+		p.pos++
+	}
+	return s, nil
+}
+
+// splitKey splits "key: rest" (or "key:"), with an optionally quoted key.
+// ok is false when text is not a mapping entry.
+func splitKey(text string) (key, rest string, ok bool, err error) {
+	if text == "" || text[0] == '[' || text[0] == '{' {
+		return "", "", false, nil
+	}
+	if text[0] == '"' || text[0] == '\'' {
+		k, n, err := quoted(text)
+		if err != nil {
+			return "", "", false, err
+		}
+		after := text[n:]
+		if after == ":" || strings.HasPrefix(after, ": ") {
+			return k, strings.TrimSpace(after[1:]), true, nil
+		}
+		return "", "", false, nil
+	}
+	for i := 0; i < len(text); i++ {
+		if text[i] == ':' && (i == len(text)-1 || text[i+1] == ' ') {
+			return strings.TrimSpace(text[:i]), strings.TrimSpace(text[i+1:]), true, nil
+		}
+	}
+	return "", "", false, nil
+}
+
+// parseInline parses a scalar or single-line flow collection.
+func parseInline(s string) (interface{}, error) {
+	f := &flow{s: s}
+	v, err := f.value()
+	if err != nil {
+		return nil, err
+	}
+	f.space()
+	if f.i < len(f.s) {
+		return nil, fmt.Errorf("unexpected %q after value", f.s[f.i:])
+	}
+	return v, nil
+}
+
+// flow is a cursor over an inline value.
+type flow struct {
+	s string
+	i int
+}
+
+func (f *flow) space() {
+	for f.i < len(f.s) && f.s[f.i] == ' ' {
+		f.i++
+	}
+}
+
+// value parses one inline value. Inside a flow collection plain scalars end
+// at ',', ']' or '}'.
+func (f *flow) value() (interface{}, error) {
+	return f.valueIn(false)
+}
+
+func (f *flow) valueIn(inFlow bool) (interface{}, error) {
+	f.space()
+	if f.i >= len(f.s) {
+		return nil, nil
+	}
+	switch f.s[f.i] {
+	case '[':
+		return f.seq()
+	case '{':
+		return f.mapping()
+	case '"', '\'':
+		v, n, err := quoted(f.s[f.i:])
+		if err != nil {
+			return nil, err
+		}
+		f.i += n
+		return v, nil
+	}
+	start := f.i
+	for f.i < len(f.s) {
+		c := f.s[f.i]
+		if inFlow && (c == ',' || c == ']' || c == '}') {
+			break
+		}
+		if inFlow && c == ':' && (f.i+1 == len(f.s) || f.s[f.i+1] == ' ') {
+			break
+		}
+		f.i++
+	}
+	return plain(strings.TrimSpace(f.s[start:f.i])), nil
+}
+
+func (f *flow) seq() (interface{}, error) {
+	f.i++ // '['
+	out := []interface{}{}
+	for {
+		f.space()
+		if f.i < len(f.s) && f.s[f.i] == ']' {
+			f.i++
+			return out, nil
+		}
+		v, err := f.valueIn(true)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+		if err := f.sep(']'); err != nil {
+			return nil, err
+		}
+		if f.s[f.i-1] == ']' {
+			return out, nil
+		}
+	}
+}
+
+func (f *flow) mapping() (interface{}, error) {
+	f.i++ // '{'
+	out := map[string]interface{}{}
+	for {
+		f.space()
+		if f.i < len(f.s) && f.s[f.i] == '}' {
+			f.i++
+			return out, nil
+		}
+		k, err := f.valueIn(true)
+		if err != nil {
+			return nil, err
+		}
+		key, ok := k.(string)
+		if !ok {
+			key = fmt.Sprint(k)
+		}
+		f.space()
+		if f.i >= len(f.s) || f.s[f.i] != ':' {
+			return nil, fmt.Errorf("missing ':' after key %q", key)
+		}
+		f.i++
+		v, err := f.valueIn(true)
+		if err != nil {
+			return nil, err
+		}
+		if _, dup := out[key]; dup {
+			return nil, fmt.Errorf("duplicate key %q", key)
+		}
+		out[key] = v
+		if err := f.sep('}'); err != nil {
+			return nil, err
+		}
+		if f.s[f.i-1] == '}' {
+			return out, nil
+		}
+	}
+}
+
+// sep consumes ',' or the closing bracket.
+func (f *flow) sep(closing byte) error {
+	f.space()
+	if f.i >= len(f.s) {
+		return fmt.Errorf("unterminated flow collection, missing %q", closing)
+	}
+	if c := f.s[f.i]; c == ',' || c == closing {
+		f.i++
+		return nil
+	}
+	return fmt.Errorf("expected ',' or %q, got %q", closing, f.s[f.i:])
+}
+
+// quoted parses a quoted scalar at the start of s and returns it with the
+// number of bytes consumed.
+func quoted(s string) (string, int, error) {
+	q := s[0]
+	for i := 1; i < len(s); i++ {
+		switch {
+		case q == '"' && s[i] == '\\':
+			i++
+		case q == '\'' && s[i] == '\'' && i+1 < len(s) && s[i+1] == '\'':
+			i++
+		case s[i] == q:
+			if q == '\'' {
+				return strings.ReplaceAll(s[1:i], "''", "'"), i + 1, nil
+			}
+			v, err := strconv.Unquote(s[:i+1])
+			if err != nil {
+				return "", 0, fmt.Errorf("bad double-quoted string %s", s[:i+1])
+			}
+			return v, i + 1, nil
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated quoted string %s", s)
+}
+
+// plain resolves a plain scalar the way YAML 1.2's core schema does: null,
+// booleans, integers and floats; anything else is a string.
+func plain(s string) interface{} {
+	switch s {
+	case "", "~", "null", "Null", "NULL":
+		return nil
+	case "true", "True", "TRUE":
+		return true
+	case "false", "False", "FALSE":
+		return false
+	}
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return n
+	}
+	if c := s[0]; (c >= '0' && c <= '9') || c == '-' || c == '+' || c == '.' {
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return f
+		}
+	}
+	return s
+}
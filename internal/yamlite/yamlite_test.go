@@ -0,0 +1,136 @@
+package yamlite
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse_Document(t *testing.T) {
+	src := `---
+# logging config
+queue_size: 2000
+block_timeout: 25ms   # trailing comment
+shed_thresholds: {debug: 0.5, info: 0.75}
+console:
+  enabled: false
+  omit_levels: [debug, "info"]
+file:
+  base_dir: '/var/log/it''s'
+  per_level:
+    error: errors.log
+sinks:
+- type: betterstack
+  endpoint: https://in.logs.betterstack.com/#x
+  options:
+    region: eu
+-   type: kafka
+    delivery: {buffer_size: 10}
+facility_levels:
+  "SIP.*": debug
+  "*": info
+patterns:
+  -
+    regex: "\\bsecret=\\S+"
+  - - nested
+    - list
+empty:
+`
+	got, err := Parse([]byte(src))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	want := map[string]interface{}{
+		"queue_size":      int64(2000),
+		"block_timeout":   "25ms",
+		"shed_thresholds": map[string]interface{}{"debug": 0.5, "info": 0.75},
+		"console": map[string]interface{}{
+			"enabled":     false,
+			"omit_levels": []interface{}{"debug", "info"},
+		},
+		"file": map[string]interface{}{
+			"base_dir":  "/var/log/it's",
+			"per_level": map[string]interface{}{"error": "errors.log"},
+		},
+		"sinks": []interface{}{
+			map[string]interface{}{
+				"type":     "betterstack",
+				"endpoint": "https://in.logs.betterstack.com/#x",
+				"options":  map[string]interface{}{"region": "eu"},
+			},
+			map[string]interface{}{
+				"type":     "kafka",
+				"delivery": map[string]interface{}{"buffer_size": int64(10)},
+			},
+		},
+		"facility_levels": map[string]interface{}{"SIP.*": "debug", "*": "info"},
+		"patterns": []interface{}{
+			map[string]interface{}{"regex": `\bsecret=\S+`},
+			[]interface{}{"nested", "list"},
+		},
+		"empty": nil,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Parse =\n%#v\nwant\n%#v", got, want)
+	}
+}
+
+func TestParse_Scalars(t *testing.T) {
+	tests := []struct {
+		in   string
+		want interface{}
+	}{
+		{"v: 42", int64(42)},
+		{"v: -7", int64(-7)},
+		{"v: 1.5", 1.5},
+		{"v: true", true},
+		{"v: False", false},
+		{"v: ~", nil},
+		{"v: null", nil},
+		{`v: "42"`, "42"},
+		{`v: "tab\there"`, "tab\there"},
+		{"v: 10.0.0.5:4000", "10.0.0.5:4000"},
+		{"v: drop_new", "drop_new"},
+		{"v: a # comment", "a"},
+		{"v: a#b", "a#b"},
+	}
+	for _, tt := range tests {
+		got, err := Parse([]byte(tt.in))
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.in, err)
+			continue
+		}
+		if v := got.(map[string]interface{})["v"]; !reflect.DeepEqual(v, tt.want) {
+			t.Errorf("Parse(%q) = %#v, want %#v", tt.in, v, tt.want)
+		}
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+	}{
+		{"tab_indent", "a:\n\tb: 1", "tabs"},
+		{"bad_indent", "a: 1\n  b: 2", "line 2: unexpected indentation"},
+		{"duplicate", "a: 1\na: 2", `duplicate key "a"`},
+		{"not_mapping", "a: 1\njust text", "line 2: expected"},
+		{"unterminated_flow", "a: [1, 2", "unterminated"},
+		{"unterminated_quote", `a: "x`, "unterminated quoted"},
+		{"multi_doc", "a: 1\n---\nb: 2", "multiple documents"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.in))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParse_Empty(t *testing.T) {
+	got, err := Parse([]byte("# nothing\n\n"))
+	if err != nil || got != nil {
+		t.Errorf("Parse(empty) = %v, %v; want nil, nil", got, err)
+	}
+}
@@ -4,195 +4,253 @@ package clog
 import (
 	"context"
 	"fmt"
+	"strings"
 	"sync"
+	"sync/atomic"
 
 	"github.com/LastBotInc/coralie-logging-go/pkg/pcmlog"
 )
 
 // agent manages the logging agent goroutine and processes log events.
 type agent struct {
-	cfg         Config
-	queue       chan Event
-	done        chan struct{}
-	wg          sync.WaitGroup
-	shutdown    bool
-	mu          sync.Mutex
-	sinks       []Sink
+	cfg    atomic.Pointer[Config] // replaced as a whole by Reconfigure
+	queue  *eventQueue            // nil in synchronous mode
+	done   chan struct{}
+	exited chan struct{} // closed when run returns
+	wg     sync.WaitGroup
+	closed atomic.Bool // set once by stop
+	mu     sync.Mutex
+	sinks  []Sink // written on the agent goroutine under mu
+	// activeSinks mirrors sinks for readers on other goroutines (Enabled).
+	activeSinks atomic.Pointer[[]Sink]
 	dedupe      *dedupeState
-	audioWriter interface {
-		WritePCM16([]int16) error
-		WriteBytesPCM16LE([]byte) error
-		Flush() error
-		Close() error
-	}
+	stats       *statsState
+	seq         atomic.Uint64
+	reconfMu    sync.Mutex // serializes Reconfigure calls
+	levels      atomic.Pointer[levelTable]
+	levelsMu    sync.Mutex // serializes SetFacilityLevel copy-on-write
+
+	// synchronous is Config.Synchronous: there is no queue or agent
+	// goroutine, and syncMu serializes the pipeline on the callers' goroutines.
+	synchronous bool
+	syncMu      sync.Mutex
+
+	// redactor holds Config.Redaction's extra patterns (nil without any).
+	// Used and replaced on the agent goroutine.
+	redactor *configRedactor
+
+	// diag rate-limits reports on Config.DiagnosticsOutput (see diagf).
+	diag diagnostics
+
+	// hooks is Config.Hooks with panic bookkeeping. Used and replaced on
+	// the agent goroutine.
+	hooks *hookSet
+	// notices are events about the logger itself raised inside the
+	// pipeline, processed after the current event (see addNotice).
+	noticeMu   sync.Mutex
+	notices    []Event
+	hasNotices atomic.Bool
+
+	// own records the agent and sink worker goroutines, so that a log call
+	// made from one of them never waits for the queue (see tryEnqueue).
+	own ownGoroutines
+
+	audioMu     sync.RWMutex
+	audioWriter audioOutput
+}
+
+// audioOutput is the audio log writer (a *pcmlog.Writer).
+type audioOutput interface {
+	WritePCM16([]int16) error
+	WriteBytesPCM16LE([]byte) error
+	Flush() error
+	Close() error
 }
 
-// newAgent creates a new agent with the given configuration.
+// defaultBitsPerSample is used when AudioConfig.BitsPerSample is 0.
+const defaultBitsPerSample = 16
+
+// newAgent creates a new agent with the given configuration and its own,
+// zeroed statistics.
 func newAgent(cfg Config) (*agent, error) {
-	a := &agent{
-		cfg:   cfg,
-		queue: make(chan Event, cfg.QueueSize),
-		done:  make(chan struct{}),
-	}
+	return newAgentWithStats(cfg, &statsState{})
+}
 
-	// Build sinks: console and file from existing config (backward compatible)
-	if cfg.Console.Enabled {
-		a.sinks = append(a.sinks, newConsoleSink(cfg.Console))
+// newAgentWithStats creates a new agent that records into stats. The default
+// logger passes globalStats so GetStats survives Shutdown/Init cycles.
+func newAgentWithStats(cfg Config, stats *statsState) (*agent, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
 	}
-	if cfg.File.BaseDir != "" {
-		fs, err := newFileSink(cfg.File)
-		if err != nil {
-			return nil, err
-		}
-		if fs != nil {
-			a.sinks = append(a.sinks, fs)
-		}
+	a := &agent{
+		done:        make(chan struct{}),
+		exited:      make(chan struct{}),
+		stats:       stats,
+		synchronous: cfg.Synchronous,
+	}
+	a.cfg.Store(&cfg)
+	a.levels.Store(newLevelTable(cfg.FacilityLevels))
+	a.redactor = newConfigRedactor(cfg.Redaction)
+	a.hooks = newHookSet(cfg.Hooks, stats)
+	if !cfg.Synchronous {
+		a.queue = newEventQueue(cfg.QueueSize, cfg.PriorityReserve, a.done)
 	}
-	// Additional sinks from Config.Sinks (e.g. BetterStack) are added in buildExtraSinks
-	extra, err := buildExtraSinks(cfg.Sinks)
+
+	// Build sinks first so a bad sink config fails before anything starts.
+	built, err := buildSinks(cfg, nil)
 	if err != nil {
 		return nil, err
 	}
-	a.sinks = append(a.sinks, extra...)
 
 	// Initialize deduplication
 	a.dedupe = newDedupeState(cfg.Dedupe)
 
 	// Initialize audio writer
-	if cfg.Audio.Enabled {
-		pcmlogCfg := pcmlog.Config{
-			Enabled:         cfg.Audio.Enabled,
-			SampleRate:      cfg.Audio.SampleRate,
-			Channels:        cfg.Audio.Channels,
-			BitsPerSample:   cfg.Audio.BitsPerSample,
-			OutputDir:       cfg.Audio.OutputDir,
-			FilenamePattern: cfg.Audio.FilenamePattern,
-		}
-		audioWriter, err := pcmlog.NewWriter(pcmlogCfg)
-		if err != nil {
-			return nil, fmt.Errorf("failed to create audio writer: %w", err)
-		}
-		if audioWriter != nil {
-			a.audioWriter = audioWriter
-		}
+	a.audioWriter, err = newAudioOutput(cfg.Audio)
+	if err != nil {
+		closeSinks(built)
+		return nil, err
 	}
 
+	a.sinks = startSinks(built, stats, cfg.Synchronous, a.newSinkSupervisor(cfg), nil)
+	active := a.sinks
+	a.activeSinks.Store(&active)
+
+	if cfg.Synchronous {
+		close(a.exited)
+		return a, nil
+	}
 	a.wg.Add(1)
 	go a.run()
 	return a, nil
 }
 
-// buildExtraSinks builds sinks from Config.Sinks (e.g. BetterStack). Returns nil slice on no config or error.
-func buildExtraSinks(cfgs []SinkConfig) ([]Sink, error) {
-	if len(cfgs) == 0 {
+// newAudioOutput creates the audio writer for cfg, or nil when audio logging
+// is disabled.
+func newAudioOutput(cfg AudioConfig) (audioOutput, error) {
+	if !cfg.Enabled {
 		return nil, nil
 	}
-	var out []Sink
-	for _, c := range cfgs {
-		switch c.Type {
-		case "betterstack":
-			s, err := newBetterStackSink(c)
-			if err != nil {
-				return nil, err
-			}
-			if s != nil {
-				out = append(out, s)
-			}
-		default:
-			// Unknown type: skip (or could return error)
-			continue
-		}
+	if cfg.BitsPerSample == 0 {
+		cfg.BitsPerSample = defaultBitsPerSample
 	}
-	return out, nil
-}
-
-// enqueue attempts to enqueue an event, applying drop policy if queue is full.
-func (a *agent) enqueue(e Event) bool {
-	a.mu.Lock()
-	shutdown := a.shutdown
-	a.mu.Unlock()
-	if shutdown {
-		return false
+	w, err := pcmlog.NewWriter(pcmlog.Config{
+		Enabled:         cfg.Enabled,
+		SampleRate:      cfg.SampleRate,
+		Channels:        cfg.Channels,
+		BitsPerSample:   cfg.BitsPerSample,
+		OutputDir:       cfg.OutputDir,
+		FilenamePattern: cfg.FilenamePattern,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create audio writer: %w", err)
 	}
-
-	select {
-	case a.queue <- e:
-		return true
-	default:
-		// Queue is full, apply drop policy
-		if a.cfg.DropPolicy == "drop_old" {
-			// Try to drop oldest by receiving one
-			select {
-			case <-a.queue:
-				// Dropped one, now try to enqueue
-				select {
-				case a.queue <- e:
-					return true
-				default:
-					return false
-				}
-			default:
-				return false
-			}
-		}
-		// drop_new: just drop the new event
-		return false
+	if w == nil {
+		return nil, nil
 	}
+	return w, nil
 }
 
-// run is the main agent loop that processes events.
+// run is the main agent loop: it takes events off the queue in batches and
+// processes them in order, parking when the queue is empty.
 func (a *agent) run() {
 	defer a.wg.Done()
+	defer close(a.exited)
+	defer a.own.enter()()
 
+	batch := make([]Event, 0, dequeueBatch)
 	for {
+		batch = a.queue.popBatch(batch[:0])
+		for _, e := range batch {
+			a.handle(e)
+		}
+		clear(batch)
+		if len(batch) > 0 {
+			continue
+		}
 		select {
 		case <-a.done:
-			// Drain remaining events
+			// stop closed the queue first, so whatever is left is final.
 			for {
-				select {
-				case e := <-a.queue:
-					a.processEvent(e)
-				default:
-					// Flush dedupe summary before exiting
-					a.flushDedupeSummary()
-					return
+				batch = a.queue.popBatch(batch[:0])
+				if len(batch) == 0 {
+					break
+				}
+				for _, e := range batch {
+					a.handle(e)
 				}
+				clear(batch)
 			}
-		case e := <-a.queue:
-			a.processEvent(e)
+			a.flushDedupeSummary()
+			return
+		default:
 		}
+		a.queue.wait()
+	}
+}
+
+// handle processes one item taken off the queue (or, in synchronous mode,
+// passed to handleInline): a control request (see Reconfigure) or a log event.
+func (a *agent) handle(e Event) {
+	if e.ctl != nil {
+		e.ctl.apply()
+		close(e.ctl.done)
+	} else {
+		a.processEvent(e)
 	}
+	a.processNotices()
 }
 
 // processEvent processes a single log event.
 //
 // Ordering is security-critical (LAS-1488, Gemini + CodeRabbit review):
 //
-//  1. Bound oversized string params BEFORE formatting. A multi-megabyte %s arg
+//  1. Resolve Lazy params (only when some hook or sink will take the event),
+//     then bound oversized string params BEFORE formatting. A multi-megabyte %s arg
 //     would otherwise force a giant fmt.Sprintf allocation/concat on the agent
 //     goroutine before any truncation. boundParams truncates such params (with a
 //     marker) up front, capping per-call work; Redact's own length guard stays as
 //     a backstop. The caller's Params slice is never mutated.
-//  2. Format the (bounded) message.
-//  3. Dedupe on the RAW formatted string. Two distinct callers that differ only
-//     in PII (participant_id=1111111@.. vs 2222222@..) must NOT collapse into one
-//     dedupe key, or the second caller is silently suppressed -- blinding ops to
-//     concurrent calls. So the dedupe key is computed BEFORE redaction.
-//  4. Only after the suppress check passes do we redact the formatted string once.
-//     Hooks receive an Event whose Message is that single redacted string with
-//     Params cleared -- so a hook that re-formats or serializes the Event cannot
-//     recombine PII split across Message+Params (e.g. "%s@%s" + ["alice","x.com"]).
-//     The same redacted string feeds every sink.
+//  2. Separate structured Field values from the printf args and format the
+//     (bounded) message.
+//  3. Dedupe on the RAW formatted string (plus raw fields). Two distinct callers
+//     that differ only in PII (participant_id=1111111@.. vs 2222222@..) must NOT
+//     collapse into one dedupe key, or the second caller is silently suppressed --
+//     blinding ops to concurrent calls. So the dedupe key is computed BEFORE
+//     redaction.
+//  4. Only after the suppress check passes do we redact the formatted string once,
+//     and each string-like field individually. Hooks receive an Event whose
+//     Message is that single redacted string with Params cleared -- so a hook that
+//     re-formats or serializes the Event cannot recombine PII split across
+//     Message+Params (e.g. "%s@%s" + ["alice","x.com"]). The same redacted Event
+//     feeds every sink.
 func (a *agent) processEvent(e Event) {
+	// Evaluate Lazy params, but only if some hook or sink will take the event.
+	e.Params = a.resolveLazy(e)
+
 	// Bound oversized string params before formatting (DoS guard, pre-Sprintf).
 	e.Params = boundParams(e.Params)
 
-	// Format the (bounded) message.
-	formatted := a.formatMessage(e)
+	// Pull structured fields out of the params; bound fields come first.
+	params, fields := splitFields(e.Params)
+	e.Params = params
+	if len(fields) > 0 {
+		e.Fields = append(e.Fields[:len(e.Fields):len(e.Fields)], fields...)
+	}
+
+	// Format the (bounded) message into a pooled buffer; the raw fields
+	// follow it to complete the dedupe key.
+	buf := getBuffer()
+	defer buf.free()
+	buf.b = a.appendMessage(buf.b, e)
+	msgLen := len(buf.b)
+	if len(e.Fields) > 0 {
+		buf.b = appendFieldsText(buf.b, e.Fields)
+	}
 
 	// Check deduplication on the RAW (pre-redaction) formatted string so distinct
 	// callers are not collapsed by redaction tokens.
-	shouldSuppress, shouldEmitSummary := a.dedupe.check(e.Level, e.Iface, formatted)
+	shouldSuppress, shouldEmitSummary := a.dedupe.check(e, buf.b)
 
 	// Emit summary if needed.
 	if shouldEmitSummary {
@@ -207,26 +265,105 @@ func (a *agent) processEvent(e Event) {
 	// Centralized PII redaction (LAS-1488 layer #1). Redact the formatted string
 	// once, only after the dedupe suppress check, so neither the sinks nor the
 	// hooks ever see raw caller PII.
-	formattedRedacted := Redact(formatted)
+	formatted := bytesString(buf.b[:msgLen])
+	out := e
+	out.Message = a.redact(formatted)
+	out.Params = nil
+	out.Fields = a.redactFields(e.Fields)
+	out.Caller = formatCaller(e.pc)
+	out.pc = 0
+
+	// Without a match the redacted message is still formatted, which points
+	// into buf: anything that may keep the Event gets a copy.
+	borrowed := out.Message == formatted
+	if borrowed && a.hasHooks(e.Level) {
+		out.Message = strings.Clone(out.Message)
+		borrowed = false
+	}
 
-	// Hand hooks the redacted formatted string with Params cleared, so a hook that
+	// Hand hooks the redacted Event with Params cleared, so a hook that
 	// re-formats/serializes the Event cannot reconstruct PII from the fragments.
-	hookEvent := e
-	hookEvent.Message = formattedRedacted
-	hookEvent.Params = nil
-	a.callHooks(hookEvent)
+	a.callHooks(out)
+	a.writeSinks(out, borrowed)
 
-	for _, sink := range a.sinks {
-		sink.Write(e.Level, e.Iface, formattedRedacted)
+	// Record emitted
+	a.stats.recordEmitted()
+}
+
+// redactFields returns fields redacted. The input slice may be shared with a
+// child logger's bound fields, so it is never modified: a copy is made as soon
+// as redaction changes a field, and fields itself is returned when none
+// changed.
+func (a *agent) redactFields(fields []Field) []Field {
+	if len(fields) == 0 {
+		return nil
+	}
+	var out []Field
+	for i, f := range fields {
+		r := f.redacted(a.redact)
+		if out == nil {
+			// redacted only rewrites the text or turns the kind into
+			// FieldString.
+			if r.Kind == f.Kind && r.str == f.str {
+				continue
+			}
+			out = make([]Field, len(fields))
+			copy(out, fields[:i])
+		}
+		out[i] = r
+	}
+	if out == nil {
+		return fields
 	}
+	return out
+}
 
-	// Record emitted
-	recordEmitted()
+// writeSinks hands a processed Event to every sink: built-in sinks get the
+// line rendered once per format for all sinks using it, other EventSinks get
+// the Event itself, plain Sinks get the message with fields (and caller)
+// appended as key=value pairs. borrowed says e.Message points into a pooled
+// buffer; it is copied before a sink that may keep it sees it.
+func (a *agent) writeSinks(e Event, borrowed bool) {
+	var r *rendering
+	var flat string
+	flattened := false
+	for _, sink := range a.sinks {
+		if rs, ok := sink.(renderedSink); ok {
+			if f, ok := rs.lineFormat(); ok {
+				if r == nil {
+					r = newRendering()
+				}
+				// Sinks filter by level before reading the line, so
+				// it is only rendered for those that take the event.
+				if sinkEnabled(sink, e.Level, e.Iface) {
+					r.render(e, f)
+				}
+				rs.writeRendered(e, r)
+				continue
+			}
+		}
+		if borrowed {
+			e.Message = strings.Clone(e.Message)
+			borrowed = false
+		}
+		if es, ok := sink.(EventSink); ok {
+			es.WriteEvent(e)
+			continue
+		}
+		if !flattened {
+			flat = flattenEvent(e)
+			flattened = true
+		}
+		sink.Write(e.Level, e.Iface, flat)
+	}
+	if r != nil {
+		r.release()
+	}
 }
 
 // boundParams returns a Params slice in which every string longer than
 // maxRedactLen has been truncated (with a "…<truncated N bytes>" marker) so the
-// downstream fmt.Sprintf in formatMessage cannot be forced into a multi-megabyte
+// downstream fmt.Appendf in appendMessage cannot be forced into a multi-megabyte
 // allocation/concat by an attacker-controlled %s arg. The caller's slice is never
 // mutated: a fresh slice is allocated only when at least one param needs bounding;
 // otherwise the original slice is returned unchanged (no allocation, common case).
@@ -258,30 +395,20 @@ func boundParams(params []interface{}) []interface{} {
 // sink string and the hook Event -- as a defensive guarantee that no future format
 // (or a misconfigured %s) can leak the in-memory raw message to a sink or hook.
 func (a *agent) emitDedupeSummary() {
-	level, iface, summary, ok := a.dedupe.flushSummary()
+	summaryEvent, ok := a.dedupe.flushSummary()
 	if !ok {
 		return
 	}
 
-	summaryRedacted := Redact(summary)
-
 	// Hand hooks the redacted summary string (Params already nil for summaries),
 	// matching processEvent: hooks never see a raw, reconstructable message.
-	summaryEvent := Event{
-		Level:   level,
-		Iface:   iface,
-		Message: summaryRedacted,
-		Params:  nil,
-	}
+	summaryEvent.Message = a.redact(summaryEvent.Message)
 
 	// Call hooks
 	a.callHooks(summaryEvent)
+	a.writeSinks(summaryEvent, false)
 
-	for _, sink := range a.sinks {
-		sink.Write(level, iface, summaryRedacted)
-	}
-
-	recordEmitted()
+	a.stats.recordEmitted()
 }
 
 // flushDedupeSummary flushes any pending deduplication summary.
@@ -292,43 +419,34 @@ func (a *agent) flushDedupeSummary() {
 // callHooks invokes all applicable hooks for the event.
 func (a *agent) callHooks(e Event) {
 	// Call global hooks
-	if a.cfg.Hooks.Global != nil {
-		for _, hook := range a.cfg.Hooks.Global {
-			hook.OnLog(e)
-		}
+	for _, hook := range a.hooks.global {
+		a.callHook(hook, e)
 	}
 
 	// Call per-level hooks
-	if a.cfg.Hooks.PerLevel != nil {
-		if hooks, ok := a.cfg.Hooks.PerLevel[e.Level]; ok {
-			for _, hook := range hooks {
-				hook.OnLog(e)
-			}
-		}
+	for _, hook := range a.hooks.perLevel[e.Level] {
+		a.callHook(hook, e)
 	}
 }
 
-// formatMessage formats a log event message (message part only, no prefix).
-func (a *agent) formatMessage(e Event) string {
+// appendMessage appends a log event's formatted message (message part only,
+// no prefix) to b.
+func (a *agent) appendMessage(b []byte, e Event) []byte {
 	if len(e.Params) > 0 {
-		return fmt.Sprintf(e.Message, e.Params...)
+		return fmt.Appendf(b, e.Message, e.Params...)
 	}
-	return e.Message
+	return append(b, e.Message...)
 }
 
 // stop stops the agent and drains the queue.
 func (a *agent) stop(ctx context.Context) {
-	a.mu.Lock()
-	alreadyShutdown := a.shutdown
-	if !alreadyShutdown {
-		a.shutdown = true
-		close(a.done)
-	}
-	a.mu.Unlock()
-
-	if alreadyShutdown {
+	if a.closed.Swap(true) {
 		return
 	}
+	if a.queue != nil {
+		a.queue.close()
+	}
+	close(a.done)
 
 	// Wait for agent to finish or context timeout
 	done := make(chan struct{})
@@ -341,15 +459,47 @@ func (a *agent) stop(ctx context.Context) {
 	case <-done:
 	case <-ctx.Done():
 	}
-
-	for _, sink := range a.sinks {
-		sink.Flush()
-		sink.Close()
+	if a.synchronous {
+		// No agent goroutine to flush the dedupe summary on exit; callers
+		// still inside handleInline finish first.
+		a.syncMu.Lock()
+		a.flushDedupeSummary()
+		a.syncMu.Unlock()
 	}
 
+	a.mu.Lock()
+	sinks := a.sinks
+	a.mu.Unlock()
+	closeStartedSinks(ctx, sinks)
+
 	// Flush and close audio writer
+	a.audioMu.Lock()
 	if a.audioWriter != nil {
 		_ = a.audioWriter.Flush()
 		_ = a.audioWriter.Close()
+		a.audioWriter = nil
+	}
+	a.audioMu.Unlock()
+}
+
+// closeStartedSinks closes sinks returned by startSinks. Asynchronous sinks
+// drain their buffers in parallel, bounded by ctx; their workers flush and
+// close the wrapped sinks. Synchronous ones are flushed and closed in place.
+func closeStartedSinks(ctx context.Context, sinks []Sink) {
+	for _, sink := range sinks {
+		if as, ok := sink.(*asyncSink); ok {
+			as.beginClose()
+			continue
+		}
+		sink.Flush()
+		sink.Close()
+	}
+	for _, sink := range sinks {
+		if as, ok := sink.(*asyncSink); ok {
+			select {
+			case <-as.done:
+			case <-ctx.Done():
+			}
+		}
 	}
 }
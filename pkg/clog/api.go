@@ -10,17 +10,27 @@ import (
 const defaultIface = "Application"
 
 var (
-	globalAgent *agent
-	initOnce    sync.Once
-	initMu      sync.RWMutex
-	shutdownMu  sync.Mutex
-	shutdown    bool
+	defaultLogger *Logger
+	initOnce      sync.Once
+	initMu        sync.RWMutex
+	shutdownMu    sync.Mutex
+	shutdown      bool
 )
 
 // Init initializes the logger with the given configuration.
 // It is safe to call multiple times, but only the first call takes effect.
 // After Shutdown, Init can be called again to reinitialize.
+// Init panics if the Config is invalid; use InitE to get the error instead.
 func Init(cfg Config) {
+	if err := InitE(cfg); err != nil {
+		panic(fmt.Sprintf("failed to initialize logger: %v", err))
+	}
+}
+
+// InitE is Init returning an error instead of panicking. The Config is
+// checked with Config.Validate, so the error lists every problem found. On
+// error the default logger stays uninitialized and InitE may be called again.
+func InitE(cfg Config) error {
 	shutdownMu.Lock()
 	wasShutdown := shutdown
 	shutdownMu.Unlock()
@@ -30,18 +40,23 @@ func Init(cfg Config) {
 		initOnce = sync.Once{}
 	}
 
+	var err error
 	initOnce.Do(func() {
 		initMu.Lock()
 		defer initMu.Unlock()
-		agent, err := newAgent(cfg)
-		if err != nil {
-			panic(fmt.Sprintf("failed to initialize logger: %v", err))
-		}
-		globalAgent = agent
+		var logger *Logger
+		logger, err = newLogger(cfg, &globalStats)
 		shutdownMu.Lock()
-		shutdown = false
+		// A failed Init leaves the logger as if shut down, so the next
+		// Init resets initOnce and tries again.
+		shutdown = err != nil
 		shutdownMu.Unlock()
+		if err != nil {
+			return
+		}
+		defaultLogger = logger
 	})
+	return err
 }
 
 // Shutdown gracefully shuts down the logger.
@@ -53,107 +68,69 @@ func Shutdown(ctx context.Context) {
 	}
 	shutdown = true
 
-	initMu.RLock()
-	agent := globalAgent
-	initMu.RUnlock()
-
-	if agent != nil {
-		agent.stop(ctx)
+	logger := Default()
+	if logger != nil {
+		logger.Shutdown(ctx)
 		initMu.Lock()
-		globalAgent = nil
+		defaultLogger = nil
 		initMu.Unlock()
 	}
 }
 
 // IsInitialized returns whether the logger has been initialized.
 func IsInitialized() bool {
-	initMu.RLock()
-	defer initMu.RUnlock()
-	return globalAgent != nil
+	return Default() != nil
 }
 
-// log enqueues a log event at the specified level.
-func log(level Level, iface, msg string, params ...interface{}) {
-	if iface == "" {
-		iface = defaultIface
-	}
-
+// Default returns the package-level Logger created by Init, or nil if the
+// logger is not initialized. Logging through a nil *Logger is a no-op.
+func Default() *Logger {
 	initMu.RLock()
-	agent := globalAgent
-	initMu.RUnlock()
-
-	if agent == nil {
-		return
-	}
-
-	event := Event{
-		Level:   level,
-		Iface:   iface,
-		Message: msg,
-		Params:  params,
-	}
-
-	if agent.enqueue(event) {
-		recordAccepted()
-	} else {
-		recordDrop(level)
-	}
+	defer initMu.RUnlock()
+	return defaultLogger
 }
 
 // Debug logs a debug message.
 func Debug(iface, msg string, params ...interface{}) {
-	log(LevelDebug, iface, msg, params...)
+	Default().log(LevelDebug, iface, msg, params)
 }
 
 // Info logs an info message.
 func Info(iface, msg string, params ...interface{}) {
-	log(LevelInfo, iface, msg, params...)
+	Default().log(LevelInfo, iface, msg, params)
 }
 
 // Success logs a success message.
 func Success(iface, msg string, params ...interface{}) {
-	log(LevelSuccess, iface, msg, params...)
+	Default().log(LevelSuccess, iface, msg, params)
 }
 
 // Warning logs a warning message.
 func Warning(iface, msg string, params ...interface{}) {
-	log(LevelWarning, iface, msg, params...)
+	Default().log(LevelWarning, iface, msg, params)
 }
 
 // Fail logs a fail message.
 func Fail(iface, msg string, params ...interface{}) {
-	log(LevelFail, iface, msg, params...)
+	Default().log(LevelFail, iface, msg, params)
 }
 
 // Error logs an error message.
 func Error(iface, msg string, params ...interface{}) {
-	log(LevelError, iface, msg, params...)
+	Default().log(LevelError, iface, msg, params)
 }
 
 // Catastrophe logs a catastrophe message.
 func Catastrophe(iface, msg string, params ...interface{}) {
-	log(LevelCatastrophe, iface, msg, params...)
+	Default().log(LevelCatastrophe, iface, msg, params)
 }
 
 // AudioWritePCM16 writes PCM16 frames to the audio log.
 func AudioWritePCM16(frames []int16) {
-	initMu.RLock()
-	agent := globalAgent
-	initMu.RUnlock()
-
-	if agent != nil && agent.audioWriter != nil {
-		_ = agent.audioWriter.WritePCM16(frames)
-	}
+	Default().AudioWritePCM16(frames)
 }
 
 // AudioWriteBytesPCM16LE writes PCM16 little-endian bytes to the audio log.
 func AudioWriteBytesPCM16LE(data []byte) {
-	initMu.RLock()
-	agent := globalAgent
-	initMu.RUnlock()
-
-	if agent != nil && agent.audioWriter != nil {
-		_ = agent.audioWriter.WriteBytesPCM16LE(data)
-	}
+	Default().AudioWriteBytesPCM16LE(data)
 }
-
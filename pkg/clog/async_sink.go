@@ -0,0 +1,578 @@
+// Package clog: per-sink buffered delivery on dedicated worker goroutines.
+package clog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// errSinkClosed is reported when flushing a sink that is already closed.
+var errSinkClosed = errors.New("sink closed")
+
+// Defaults for zero DeliveryConfig fields.
+const (
+	defaultSinkBufferSize     = 1024
+	defaultSlowWriteThreshold = 10 * time.Second
+	defaultMaxBatchSize       = 256
+	defaultMaxBatchLinger     = 100 * time.Millisecond
+)
+
+// DeliveryConfig configures how the agent hands events to one sink. Every
+// sink gets its own bounded buffer and worker goroutine, so a slow or hung
+// sink only loses its own events instead of stalling the agent and the other
+// sinks. The zero value uses the defaults below.
+type DeliveryConfig struct {
+	// BufferSize is the number of events buffered for this sink (default 1024).
+	BufferSize int
+	// DropPolicy applies when the buffer is full, with the same values as
+	// Config.DropPolicy: "drop_new" (default), "drop_old", "block" (wait up to
+	// BlockTimeout) or "block_forever". Blocking stalls the agent goroutine.
+	DropPolicy string
+	// BlockTimeout is the "block" wait (default 10ms).
+	BlockTimeout time.Duration
+	// SlowWriteThreshold is the longest a single write should take (default
+	// 10s). Slower writes are counted in SinkStats.SlowWrites. It is not a
+	// timeout: a write is never abandoned, since the sink would then be
+	// written by two goroutines at once, so a hung sink stalls its worker and
+	// its buffer fills (see SinkStats.Dropped).
+	SlowWriteThreshold time.Duration
+	// MaxBatchSize is the most events handed to a BatchSink at once
+	// (default 256). Other sinks ignore it and MaxBatchLinger.
+	MaxBatchSize int
+	// MaxBatchLinger is how long a BatchSink's worker waits for more events
+	// after the first one of a batch before writing it (default 100ms).
+	MaxBatchLinger time.Duration
+}
+
+// withDefaults returns d with zero fields replaced by their defaults.
+func (d DeliveryConfig) withDefaults() DeliveryConfig {
+	if d.BufferSize == 0 {
+		d.BufferSize = defaultSinkBufferSize
+	}
+	if d.BlockTimeout == 0 {
+		d.BlockTimeout = defaultBlockTimeout
+	}
+	if d.SlowWriteThreshold == 0 {
+		d.SlowWriteThreshold = defaultSlowWriteThreshold
+	}
+	if d.MaxBatchSize == 0 {
+		d.MaxBatchSize = defaultMaxBatchSize
+	}
+	if d.MaxBatchLinger == 0 {
+		d.MaxBatchLinger = defaultMaxBatchLinger
+	}
+	return d
+}
+
+// validate rejects negative sizes and durations and unknown drop policies.
+func (d DeliveryConfig) validate() error {
+	if d.BufferSize < 0 {
+		return fmt.Errorf("negative buffer size %d", d.BufferSize)
+	}
+	if d.SlowWriteThreshold < 0 {
+		return fmt.Errorf("negative slow write threshold %v", d.SlowWriteThreshold)
+	}
+	if d.MaxBatchSize < 0 {
+		return fmt.Errorf("negative max batch size %d", d.MaxBatchSize)
+	}
+	if d.MaxBatchLinger < 0 {
+		return fmt.Errorf("negative max batch linger %v", d.MaxBatchLinger)
+	}
+	return checkDropPolicy(d.DropPolicy, d.BlockTimeout)
+}
+
+// sinkItem is one buffered event. For a lineSink, r holds the rendered line
+// (the item holds one reference) and e only Level and Iface.
+type sinkItem struct {
+	e Event
+	r *rendering
+}
+
+// sinkFlush is a flush request waiting for the first target events put on
+// the buffer to be taken off it. done must have room for one result.
+type sinkFlush struct {
+	target uint64
+	done   chan error
+}
+
+// asyncSink delivers events to an inner sink from its own worker goroutine.
+// WriteEvent never waits on the inner sink; only the buffer's drop policy
+// decides what happens when the worker falls behind. The worker owns the
+// inner sink: it is the only goroutine that writes, flushes and closes it.
+type asyncSink struct {
+	name  string
+	inner Sink
+	line  lineSink  // inner, if it is one and not a BatchSink
+	batch BatchSink // inner, if it is one
+	cfg   DeliveryConfig
+	stats *sinkCounters
+	guard sinkGuard
+	after []<-chan struct{} // see newAsyncSinkAfter
+
+	levels sinkLevels  // set by startSinks before the sink is used
+	spec   interface{} // likewise; see keepRunning
+
+	buf       chan sinkItem
+	wake      chan struct{} // nudges an idle worker to look at flushes
+	quit      chan struct{} // closed first on Close to release blocked senders
+	done      chan struct{} // closed when the worker has closed the inner sink
+	mu        sync.RWMutex  // guards closed against sends on a closed buf
+	closed    bool
+	closeOnce sync.Once
+
+	// Flush requests never go through buf, so a full buffer cannot hold up
+	// whoever asks. posted counts the events put on buf and taken those
+	// removed from it, by the worker or by drop_old; the worker answers a
+	// request once taken reaches its target.
+	posted   atomic.Uint64
+	taken    atomic.Uint64
+	flushMu  sync.Mutex
+	flushes  []sinkFlush // in target order
+	hasFlush atomic.Bool
+}
+
+// newAsyncSink wraps inner and starts its worker. cfg must be validated.
+// Failed writes, flushes and closes are counted in stats and passed to sup
+// (which may be nil), and so are panics, which quarantine the sink when they
+// keep happening.
+func newAsyncSink(name string, inner Sink, cfg DeliveryConfig, stats *sinkCounters, sup *sinkSupervisor) *asyncSink {
+	return newAsyncSinkAfter(name, inner, cfg, stats, sup, nil)
+}
+
+// newAsyncSinkAfter is newAsyncSink for a worker that buffers events but
+// writes none until every channel in after is closed (or it is closed).
+func newAsyncSinkAfter(name string, inner Sink, cfg DeliveryConfig, stats *sinkCounters, sup *sinkSupervisor, after []<-chan struct{}) *asyncSink {
+	cfg = cfg.withDefaults()
+	s := &asyncSink{
+		name:  name,
+		inner: inner,
+		cfg:   cfg,
+		stats: stats,
+		guard: newSinkGuard(name, stats, sup),
+		after: after,
+		buf:   make(chan sinkItem, cfg.BufferSize),
+		wake:  make(chan struct{}, 1),
+		quit:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	s.line, s.batch = deliveryKind(inner)
+	if s.batch != nil {
+		go s.runBatches()
+	} else {
+		go s.run()
+	}
+	return s
+}
+
+// deliveryKind returns inner as a BatchSink, looking through a borrowedSink,
+// or else as a lineSink. Batching wins: a BatchSink is handed Events rather
+// than rendered lines.
+func deliveryKind(inner Sink) (lineSink, BatchSink) {
+	s := inner
+	if b, ok := s.(borrowedSink); ok {
+		s = b.inner
+	}
+	if bs, ok := s.(BatchSink); ok {
+		return nil, bs
+	}
+	ls, _ := inner.(lineSink)
+	return ls, nil
+}
+
+// Write implements Sink.
+func (s *asyncSink) Write(level Level, iface, formatted string) {
+	s.WriteEvent(Event{Level: level, Iface: iface, Message: formatted})
+}
+
+// WriteEvent implements EventSink. It buffers e for the worker, applying the
+// delivery drop policy when the buffer is full.
+func (s *asyncSink) WriteEvent(e Event) {
+	if !s.levels.allow(e.Level) {
+		return
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed || s.guard.quarantined.Load() || !s.offer(sinkItem{e: e}) {
+		s.stats.dropped.Add(1)
+	}
+}
+
+// lineFormat reports the format of the wrapped sink if it is a lineSink.
+func (s *asyncSink) lineFormat() (lineFormat, bool) {
+	if s.line == nil {
+		return 0, false
+	}
+	return s.line.lineFormat(), true
+}
+
+// writeRendered buffers an event rendered in r for the wrapped lineSink,
+// holding a reference to r until the worker has written it.
+func (s *asyncSink) writeRendered(e Event, r *rendering) {
+	if !s.levels.allow(e.Level) {
+		return
+	}
+	r.retain()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed || s.guard.quarantined.Load() || !s.offer(sinkItem{e: Event{Level: e.Level, Iface: e.Iface}, r: r}) {
+		r.release()
+		s.stats.dropped.Add(1)
+	}
+}
+
+// offer puts it on the buffer according to the drop policy and counts it in
+// posted. Callers hold mu for reading.
+func (s *asyncSink) offer(it sinkItem) bool {
+	if !s.send(it) {
+		return false
+	}
+	s.posted.Add(1)
+	return true
+}
+
+// send implements offer.
+func (s *asyncSink) send(it sinkItem) bool {
+	select {
+	case s.buf <- it:
+		return true
+	default:
+	}
+	switch s.cfg.DropPolicy {
+	case dropPolicyOld:
+		select {
+		case old := <-s.buf:
+			s.taken.Add(1)
+			if old.r != nil {
+				old.r.release()
+			}
+			s.stats.dropped.Add(1)
+		default:
+		}
+		select {
+		case s.buf <- it:
+			return true
+		default:
+			return false
+		}
+	case dropPolicyBlock:
+		timer := time.NewTimer(s.cfg.BlockTimeout)
+		defer timer.Stop()
+		select {
+		case s.buf <- it:
+			return true
+		case <-timer.C:
+			return false
+		case <-s.quit:
+			return false
+		}
+	case dropPolicyBlockForever:
+		select {
+		case s.buf <- it:
+			return true
+		case <-s.quit:
+			return false
+		}
+	default:
+		return false
+	}
+}
+
+// run is the worker loop: it writes buffered events in order, answers flush
+// requests, and flushes and closes the inner sink once the buffer is closed.
+// Once the sink is quarantined, buffered events are dropped.
+func (s *asyncSink) run() {
+	defer close(s.done)
+	defer s.guard.sup.enterWorker()()
+	s.awaitPredecessors()
+	for {
+		select {
+		case it, ok := <-s.buf:
+			if !ok {
+				s.finish()
+				return
+			}
+			s.taken.Add(1)
+			s.deliver(it)
+		case <-s.wake:
+		}
+		if ready := s.readyFlushes(); ready != nil {
+			answerFlushes(ready, s.flush())
+		}
+	}
+}
+
+// awaitPredecessors waits until the workers in s.after are done, or s is
+// closed.
+func (s *asyncSink) awaitPredecessors() {
+	for _, done := range s.after {
+		select {
+		case <-done:
+		case <-s.quit:
+			return
+		}
+	}
+	s.after = nil
+}
+
+// deliver writes one buffered event, or drops it if the sink is quarantined.
+func (s *asyncSink) deliver(it sinkItem) {
+	if s.guard.quarantined.Load() {
+		s.stats.dropped.Add(1)
+	} else if it.r != nil {
+		_ = s.guard.call("write", func() error {
+			return writeLineCounted(s.line, it.e, it.r, s.cfg.SlowWriteThreshold, s.stats)
+		})
+	} else {
+		s.write(it.e)
+	}
+	if it.r != nil {
+		it.r.release()
+	}
+}
+
+// readyFlushes removes and returns the flush requests whose events have all
+// been taken off the buffer. The worker calls it between events, with
+// nothing held back, and answers them after flushing the inner sink once.
+func (s *asyncSink) readyFlushes() []sinkFlush {
+	if !s.hasFlush.Load() {
+		return nil
+	}
+	s.flushMu.Lock()
+	defer s.flushMu.Unlock()
+	taken := s.taken.Load()
+	n := 0
+	for n < len(s.flushes) && s.flushes[n].target <= taken {
+		n++
+	}
+	if n == 0 {
+		return nil
+	}
+	ready := s.flushes[:n:n]
+	s.flushes = append([]sinkFlush(nil), s.flushes[n:]...)
+	s.hasFlush.Store(len(s.flushes) > 0)
+	return ready
+}
+
+// answerFlushes sends err to every request in flushes.
+func answerFlushes(flushes []sinkFlush, err error) {
+	for _, f := range flushes {
+		f.done <- err
+	}
+}
+
+// finish flushes and closes the inner sink once the buffer is drained, and
+// answers the flush requests still waiting. A quarantined sink is still
+// closed, to release what it holds.
+func (s *asyncSink) finish() {
+	err := s.flush()
+	s.flushMu.Lock()
+	rest := s.flushes
+	s.flushes = nil
+	s.flushMu.Unlock()
+	answerFlushes(rest, err)
+	_ = s.guard.call("close", func() error { return closeSink(s.inner) })
+}
+
+// flush flushes the inner sink and reports a failure.
+func (s *asyncSink) flush() error {
+	if s.guard.quarantined.Load() {
+		return errQuarantined
+	}
+	return s.guard.call("flush", func() error { return flushSink(s.inner) })
+}
+
+// runBatches is run for a BatchSink. Events are collected until the batch
+// is full or MaxBatchLinger has passed since its first event; a flush request
+// that is ready or closing the buffer writes the partial batch first.
+func (s *asyncSink) runBatches() {
+	defer close(s.done)
+	defer s.guard.sup.enterWorker()()
+	s.awaitPredecessors()
+	batch := make([]Event, 0, s.cfg.MaxBatchSize)
+	linger := time.NewTimer(s.cfg.MaxBatchLinger)
+	linger.Stop()
+	defer linger.Stop()
+	write := func() {
+		if len(batch) == 0 {
+			return
+		}
+		linger.Stop()
+		if s.guard.quarantined.Load() {
+			s.stats.dropped.Add(int64(len(batch)))
+		} else {
+			_ = s.guard.call("write", func() error {
+				return writeBatchCounted(s.batch, batch, s.cfg.SlowWriteThreshold, s.stats)
+			})
+		}
+		clear(batch)
+		batch = batch[:0]
+	}
+	for {
+		select {
+		case it, ok := <-s.buf:
+			if !ok {
+				write()
+				s.finish()
+				return
+			}
+			s.taken.Add(1)
+			batch = append(batch, it.e)
+			if len(batch) == 1 {
+				linger.Reset(s.cfg.MaxBatchLinger)
+			}
+			if len(batch) >= s.cfg.MaxBatchSize {
+				write()
+			}
+		case <-linger.C:
+			write()
+		case <-s.wake:
+		}
+		if ready := s.readyFlushes(); ready != nil {
+			write()
+			answerFlushes(ready, s.flush())
+		}
+	}
+}
+
+// write hands one event to the inner sink and records its outcome.
+func (s *asyncSink) write(e Event) {
+	_ = s.guard.call("write", func() error {
+		return writeCounted(s.inner, e, s.cfg.SlowWriteThreshold, s.stats)
+	})
+}
+
+// writeCounted writes e to sink and counts it, and counts writes slower than
+// slowAfter, in c. It returns the sink's error if it is an ErrorWriter.
+func writeCounted(sink Sink, e Event, slowAfter time.Duration, c *sinkCounters) error {
+	start := time.Now()
+	var err error
+	switch sk := sink.(type) {
+	case ErrorWriter:
+		err = sk.WriteErr(e)
+	case EventSink:
+		sk.WriteEvent(e)
+	default:
+		sink.Write(e.Level, e.Iface, flattenEvent(e))
+	}
+	if time.Since(start) > slowAfter {
+		c.slowWrites.Add(1)
+	}
+	c.written.Add(1)
+	return err
+}
+
+// writeBatchCounted writes events to sink as one batch and counts them like
+// writeCounted; a slow batch counts as one slow write.
+func writeBatchCounted(sink BatchSink, events []Event, slowAfter time.Duration, c *sinkCounters) error {
+	start := time.Now()
+	err := sink.WriteBatch(events)
+	if time.Since(start) > slowAfter {
+		c.slowWrites.Add(1)
+	}
+	c.written.Add(int64(len(events)))
+	return err
+}
+
+// writeLineCounted writes e's line from r to sink and counts it like
+// writeCounted.
+func writeLineCounted(sink lineSink, e Event, r *rendering, slowAfter time.Duration, c *sinkCounters) error {
+	start := time.Now()
+	err := sink.writeLine(e.Level, e.Iface, r.line(sink.lineFormat()))
+	if time.Since(start) > slowAfter {
+		c.slowWrites.Add(1)
+	}
+	c.written.Add(1)
+	return err
+}
+
+// Enabled implements LevelEnabler by applying the sink's levels and asking
+// the wrapped sink; a quarantined sink is enabled for nothing.
+func (s *asyncSink) Enabled(level Level, iface string) bool {
+	return s.levels.allow(level) && s.guard.enabled(s.inner, level, iface)
+}
+
+// Flush implements Sink. It waits until every event buffered before the call
+// has been written and the inner sink flushed.
+func (s *asyncSink) Flush() {
+	if flushed, err := s.requestFlush(); err == nil {
+		_ = s.awaitFlush(context.Background(), flushed)
+	}
+}
+
+// requestFlush asks the worker to flush the inner sink once every event
+// buffered so far has been written. It never waits, even when the buffer is
+// full. The returned channel receives the inner sink's flush result.
+func (s *asyncSink) requestFlush() (chan error, error) {
+	flushed := make(chan error, 1)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		return nil, errSinkClosed
+	}
+	s.flushMu.Lock()
+	s.flushes = append(s.flushes, sinkFlush{target: s.posted.Load(), done: flushed})
+	s.hasFlush.Store(true)
+	s.flushMu.Unlock()
+	select {
+	case s.wake <- struct{}{}:
+	default: // already nudged
+	}
+	return flushed, nil
+}
+
+// awaitFlush waits for the result of requestFlush, bounded by ctx. A result
+// that is already available wins over an expired ctx.
+func (s *asyncSink) awaitFlush(ctx context.Context, flushed chan error) error {
+	select {
+	case err := <-flushed:
+		return err
+	default:
+	}
+	select {
+	case err := <-flushed:
+		return err
+	case <-s.done:
+		// Closed while the request was queued; the worker's final flush
+		// may have answered it.
+		select {
+		case err := <-flushed:
+			return err
+		default:
+			return errSinkClosed
+		}
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close implements Sink. It drains the buffer, then flushes and closes the
+// inner sink, and returns once that is done.
+func (s *asyncSink) Close() {
+	s.beginClose()
+	<-s.done
+}
+
+// beginClose stops accepting events and lets the worker drain and close the
+// inner sink; wait for s.done to know it has finished.
+func (s *asyncSink) beginClose() {
+	s.closeOnce.Do(func() {
+		close(s.quit)
+		s.mu.Lock()
+		s.closed = true
+		close(s.buf)
+		s.mu.Unlock()
+	})
+}
+
+// flattenEvent renders e for a plain Sink: the message with fields (and
+// caller) appended as key=value pairs.
+func flattenEvent(e Event) string {
+	if len(e.Fields) == 0 && e.Caller == "" {
+		return e.Message
+	}
+	return string(appendEventSuffix([]byte(e.Message), e))
+}
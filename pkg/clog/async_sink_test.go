@@ -0,0 +1,269 @@
+package clog
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// stuckSink blocks every Write until release is closed, signalling entered on
+// the first one.
+type stuckSink struct {
+	captureSink
+	entered chan struct{}
+	release chan struct{}
+}
+
+func newStuckSink() *stuckSink {
+	return &stuckSink{entered: make(chan struct{}), release: make(chan struct{})}
+}
+
+func (s *stuckSink) Write(level Level, iface, formatted string) {
+	select {
+	case <-s.entered:
+	default:
+		close(s.entered)
+	}
+	<-s.release
+	s.captureSink.Write(level, iface, formatted)
+}
+
+// newSinkTestAgent returns an agent whose only sinks are the given ones,
+// started with their delivery workers.
+func newSinkTestAgent(t *testing.T, sinks ...namedSink) *agent {
+	t.Helper()
+	cfg := DefaultConfig()
+	cfg.Console.Enabled = false
+	cfg.Dedupe.Enabled = false
+	a, err := newAgent(cfg)
+	if err != nil {
+		t.Fatalf("newAgent: %v", err)
+	}
+	a.sinks = startSinks(sinks, a.stats, false, nil, nil)
+	return a
+}
+
+func TestAsyncSink_SlowSinkDoesNotStallOthers(t *testing.T) {
+	slow := newStuckSink()
+	fast := &captureSink{}
+	a := newSinkTestAgent(t,
+		namedSink{name: "slow", sink: slow, delivery: DeliveryConfig{BufferSize: 2}},
+		namedSink{name: "fast", sink: fast},
+	)
+
+	a.enqueue(Event{Level: LevelInfo, Iface: "T", Message: "first"})
+	<-slow.entered
+	for i := 0; i < 9; i++ {
+		a.enqueue(Event{Level: LevelInfo, Iface: "T", Message: fmt.Sprintf("m%d", i)})
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for len(fast.snapshot()) < 10 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if got := len(fast.snapshot()); got != 10 {
+		t.Fatalf("fast sink got %d events while slow sink was stuck, want 10", got)
+	}
+
+	close(slow.release)
+	a.stop(context.Background())
+
+	stats := a.stats.snapshot().Sinks
+	if s := stats["slow"]; s.Written != 3 || s.Dropped != 7 {
+		t.Errorf("slow stats = %+v, want 3 written / 7 dropped", s)
+	}
+	if s := stats["fast"]; s.Written != 10 || s.Dropped != 0 {
+		t.Errorf("fast stats = %+v, want 10 written / 0 dropped", s)
+	}
+	if got := slow.snapshot(); len(got) != 3 || got[2] != "m1" {
+		t.Errorf("slow sink wrote %v, want first, m0, m1", got)
+	}
+}
+
+func TestAsyncSink_DropOldKeepsNewest(t *testing.T) {
+	inner := newStuckSink()
+	s := newAsyncSink("s", inner, DeliveryConfig{BufferSize: 2, DropPolicy: "drop_old"}, &sinkCounters{}, nil)
+	s.WriteEvent(Event{Message: "first"})
+	<-inner.entered
+	for i := 0; i < 5; i++ {
+		s.WriteEvent(Event{Message: fmt.Sprintf("m%d", i)})
+	}
+	close(inner.release)
+	s.Close()
+
+	got := inner.snapshot()
+	if strings.Join(got, ",") != "first,m3,m4" {
+		t.Errorf("wrote %v, want first,m3,m4", got)
+	}
+	if d := s.stats.dropped.Load(); d != 3 {
+		t.Errorf("dropped = %d, want 3", d)
+	}
+}
+
+func TestAsyncSink_FlushWaitsForBufferedEvents(t *testing.T) {
+	inner := &slowSink{delay: 5 * time.Millisecond}
+	s := newAsyncSink("s", inner, DeliveryConfig{SlowWriteThreshold: time.Millisecond}, &sinkCounters{}, nil)
+	for i := 0; i < 3; i++ {
+		s.WriteEvent(Event{Message: "m", Fields: []Field{Int("i", i)}})
+	}
+	s.Flush()
+	if got := inner.snapshot(); len(got) != 3 || got[2] != "m i=2" {
+		t.Errorf("after Flush wrote %v, want 3 flattened events", got)
+	}
+	if n := s.stats.slowWrites.Load(); n != 3 {
+		t.Errorf("slow writes = %d, want 3", n)
+	}
+	s.Close()
+	s.WriteEvent(Event{Message: "after close"})
+	if d := s.stats.dropped.Load(); d != 1 {
+		t.Errorf("write after Close not counted as dropped (%d)", d)
+	}
+}
+
+// slowSink sleeps in every Write.
+type slowSink struct {
+	captureSink
+	delay time.Duration
+}
+
+func (s *slowSink) Write(level Level, iface, formatted string) {
+	time.Sleep(s.delay)
+	s.captureSink.Write(level, iface, formatted)
+}
+
+func TestAsyncSink_ConfigNamesAndValidation(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Console.Enabled = false
+	cfg.Sinks = []SinkConfig{
+		{Type: "betterstack", Token: "t", Endpoint: "http://127.0.0.1:0"},
+		{Type: "betterstack", Token: "t", Endpoint: "http://127.0.0.1:0"},
+		{Type: "betterstack", Name: "audit", Token: "t", Endpoint: "http://127.0.0.1:0"},
+	}
+	l, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	l.Shutdown(context.Background())
+	for _, name := range []string{"betterstack", "betterstack#2", "audit"} {
+		if _, ok := l.Stats().Sinks[name]; !ok {
+			t.Errorf("Stats().Sinks missing %q: %v", name, l.Stats().Sinks)
+		}
+	}
+
+	cfg = DefaultConfig()
+	cfg.Console.Delivery.DropPolicy = "sometimes"
+	if _, err := New(cfg); err == nil || !strings.Contains(err.Error(), "Console.Delivery") {
+		t.Errorf("New with bad console drop policy: err = %v", err)
+	}
+
+	cfg = DefaultConfig()
+	cfg.Console.Delivery.MaxBatchSize = -1
+	if _, err := New(cfg); err == nil || !strings.Contains(err.Error(), "max batch size") {
+		t.Errorf("New with negative max batch size: err = %v", err)
+	}
+}
+
+// batchRecorder is a BatchSink recording the messages of every batch.
+type batchRecorder struct {
+	captureSink
+	bmu     sync.Mutex
+	batches [][]string
+}
+
+func (s *batchRecorder) WriteBatch(events []Event) error {
+	msgs := make([]string, len(events))
+	for i, e := range events {
+		msgs[i] = e.Message
+	}
+	s.bmu.Lock()
+	s.batches = append(s.batches, msgs)
+	s.bmu.Unlock()
+	return nil
+}
+
+func (s *batchRecorder) batchSnapshot() [][]string {
+	s.bmu.Lock()
+	defer s.bmu.Unlock()
+	return append([][]string(nil), s.batches...)
+}
+
+func TestAsyncSink_BatchesUpToMaxBatchSize(t *testing.T) {
+	inner := &batchRecorder{}
+	s := newAsyncSink("s", inner, DeliveryConfig{MaxBatchSize: 3, MaxBatchLinger: time.Hour}, &sinkCounters{}, nil)
+	defer s.Close()
+	for i := 0; i < 7; i++ {
+		s.WriteEvent(Event{Message: fmt.Sprintf("m%d", i)})
+	}
+	s.Flush() // writes the partial batch without waiting for the linger
+
+	got := fmt.Sprint(inner.batchSnapshot())
+	if want := "[[m0 m1 m2] [m3 m4 m5] [m6]]"; got != want {
+		t.Errorf("batches = %s, want %s", got, want)
+	}
+	if msgs := inner.snapshot(); len(msgs) != 0 {
+		t.Errorf("Write called for %v, want only WriteBatch", msgs)
+	}
+	if n := s.stats.written.Load(); n != 7 {
+		t.Errorf("written = %d, want 7", n)
+	}
+}
+
+func TestAsyncSink_BatchLingerWritesPartialBatch(t *testing.T) {
+	inner := &batchRecorder{}
+	s := newAsyncSink("s", inner, DeliveryConfig{MaxBatchLinger: 10 * time.Millisecond}, &sinkCounters{}, nil)
+	defer s.Close()
+	s.WriteEvent(Event{Message: "a"})
+	s.WriteEvent(Event{Message: "b"})
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		var n int
+		for _, b := range inner.batchSnapshot() {
+			n += len(b)
+		}
+		if n == 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("batches = %v after 2s, want both events written after the linger", inner.batchSnapshot())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestBatchSink_Instance(t *testing.T) {
+	for _, synchronous := range []bool{false, true} {
+		t.Run(fmt.Sprintf("synchronous=%v", synchronous), func(t *testing.T) {
+			inner := &batchRecorder{}
+			cfg := DefaultConfig()
+			cfg.Console.Enabled = false
+			cfg.Dedupe.Enabled = false
+			cfg.Synchronous = synchronous
+			if synchronous {
+				cfg.QueueSize = 0
+			}
+			cfg.Sinks = []SinkConfig{{Name: "batch", Instance: inner,
+				Delivery: DeliveryConfig{MaxBatchLinger: time.Hour}}}
+			l, err := New(cfg)
+			if err != nil {
+				t.Fatalf("New: %v", err)
+			}
+			defer l.Shutdown(context.Background())
+			l.Info("T", "one")
+			l.Info("T", "two %d", 2)
+			if err := l.Flush(context.Background()); err != nil {
+				t.Fatalf("Flush: %v", err)
+			}
+
+			want := "[[one two 2]]"
+			if synchronous {
+				want = "[[one] [two 2]]"
+			}
+			if got := fmt.Sprint(inner.batchSnapshot()); got != want {
+				t.Errorf("batches = %s, want %s", got, want)
+			}
+		})
+	}
+}
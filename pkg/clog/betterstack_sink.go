@@ -4,7 +4,8 @@ package clog
 import (
 	"bytes"
 	"context"
-	"encoding/json"
+	"fmt"
+	"io"
 	"net/http"
 	"sync"
 	"time"
@@ -12,6 +13,10 @@ import (
 
 const defaultBetterStackEndpoint = "https://in.logs.betterstack.com"
 
+// defaultBetterStackTimeout is the HTTP request timeout when
+// SinkConfig.Timeout is zero.
+const defaultBetterStackTimeout = 10 * time.Second
+
 // betterstackSink sends log events to BetterStack's HTTP ingest API.
 type betterstackSink struct {
 	client     *http.Client
@@ -23,13 +28,6 @@ type betterstackSink struct {
 	closed     bool
 }
 
-type betterstackEvent struct {
-	Dt       string `json:"dt"`
-	Level    string `json:"level"`
-	Facility string `json:"facility"`
-	Message  string `json:"message"`
-}
-
 // newBetterStackSink creates a BetterStack sink from SinkConfig. Token must be set; Endpoint defaults if empty.
 func newBetterStackSink(c SinkConfig) (*betterstackSink, error) {
 	if c.Token == "" {
@@ -39,9 +37,13 @@ func newBetterStackSink(c SinkConfig) (*betterstackSink, error) {
 	if endpoint == "" {
 		endpoint = defaultBetterStackEndpoint
 	}
+	timeout := c.Timeout
+	if timeout == 0 {
+		timeout = defaultBetterStackTimeout
+	}
 	s := &betterstackSink{
 		client: &http.Client{
-			Timeout: 10 * time.Second,
+			Timeout: timeout,
 		},
 		endpoint:   endpoint,
 		token:      c.Token,
@@ -51,36 +53,94 @@ func newBetterStackSink(c SinkConfig) (*betterstackSink, error) {
 	return s, nil
 }
 
-// Write implements Sink. Sends one JSON event per call (no batching in v1).
+// Write implements Sink. Sends one JSON event per call; the delivery worker
+// normally uses WriteBatch instead.
 func (s *betterstackSink) Write(level Level, iface, formatted string) {
+	s.WriteEvent(Event{Level: level, Iface: iface, Message: formatted})
+}
+
+// WriteEvent implements EventSink. Structured fields become top-level JSON keys.
+func (s *betterstackSink) WriteEvent(e Event) {
+	_ = s.WriteErr(e)
+}
+
+// WriteErr implements ErrorWriter: transport errors and non-2xx responses
+// (e.g. 401 for a bad token) are returned.
+func (s *betterstackSink) WriteErr(e Event) error {
+	return s.writeLine(e.Level, e.Iface, appendJSONEvent(nil, e))
+}
+
+// lineFormat implements lineSink.
+func (s *betterstackSink) lineFormat() lineFormat {
+	return lineJSON
+}
+
+// writeLine implements lineSink: it posts the JSON event.
+func (s *betterstackSink) writeLine(level Level, _ string, line []byte) error {
 	if !levelFilter(level, s.minLevel, s.omitLevels) {
-		return
+		return nil
+	}
+	return s.post(line)
+}
+
+// WriteBatch implements BatchSink: the events that pass the level filter are
+// posted as one JSON array, which the ingest API accepts like single events.
+func (s *betterstackSink) WriteBatch(events []Event) error {
+	buf := getBuffer()
+	defer buf.free()
+	buf.b = append(buf.b, '[')
+	n := 0
+	for _, e := range events {
+		if !levelFilter(e.Level, s.minLevel, s.omitLevels) {
+			continue
+		}
+		if n > 0 {
+			buf.b = append(buf.b, ',')
+		}
+		buf.b = appendJSONEvent(buf.b, e)
+		n++
+	}
+	if n == 0 {
+		return nil
 	}
+	return s.post(append(buf.b, ']'))
+}
+
+// post sends body to the ingest endpoint unless the sink is closed. A
+// response other than 2xx is an error.
+func (s *betterstackSink) post(body []byte) error {
 	s.mu.Lock()
 	if s.closed {
 		s.mu.Unlock()
-		return
+		return nil
 	}
 	s.mu.Unlock()
 
-	ev := betterstackEvent{
-		Dt:       time.Now().UTC().Format(time.RFC3339Nano),
-		Level:    level.String(),
-		Facility: iface,
-		Message:  formatted,
-	}
-	body, _ := json.Marshal(ev)
+	// The transport may still read the body after Do returns, and body is
+	// only ours until post does.
+	body = bytes.Clone(body)
 	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, s.endpoint, bytes.NewReader(body))
 	if err != nil {
-		return
+		return err
 	}
 	req.Header.Set("Authorization", "Bearer "+s.token)
 	req.Header.Set("Content-Type", "application/json")
 	resp, err := s.client.Do(req)
 	if err != nil {
-		return
+		return err
 	}
+	// Drain a little of the body so the connection can be reused.
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4<<10))
 	_ = resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("betterstack ingest: %s", resp.Status)
+	}
+	return nil
+}
+
+// Enabled implements LevelEnabler.
+func (s *betterstackSink) Enabled(level Level, _ string) bool {
+	return levelFilter(level, s.minLevel, s.omitLevels)
 }
 
 // Flush implements Sink. No-op for v1 (no buffer).
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -21,12 +22,12 @@ func TestBetterStackSink_DisabledWithoutToken(t *testing.T) {
 
 func TestBetterStackSink_Write_MinLevel(t *testing.T) {
 	var mu sync.Mutex
-	var received []betterstackEvent
+	var received []jsonLine
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
 			t.Errorf("method = %s, want POST", r.Method)
 		}
-		var ev betterstackEvent
+		var ev jsonLine
 		if err := json.NewDecoder(r.Body).Decode(&ev); err != nil {
 			t.Errorf("decode: %v", err)
 			return
@@ -68,9 +69,9 @@ func TestBetterStackSink_Write_MinLevel(t *testing.T) {
 
 func TestBetterStackSink_Write_OmitLevels(t *testing.T) {
 	var mu sync.Mutex
-	var received []betterstackEvent
+	var received []jsonLine
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		var ev betterstackEvent
+		var ev jsonLine
 		if err := json.NewDecoder(r.Body).Decode(&ev); err != nil {
 			t.Errorf("decode: %v", err)
 			return
@@ -109,3 +110,68 @@ func TestBetterStackSink_Write_OmitLevels(t *testing.T) {
 		t.Errorf("received %d events, want 1", n)
 	}
 }
+
+func TestBetterStackSink_WriteBatch(t *testing.T) {
+	var mu sync.Mutex
+	var requests [][]jsonLine
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var evs []jsonLine
+		if err := json.NewDecoder(r.Body).Decode(&evs); err != nil {
+			t.Errorf("decode: %v", err)
+			return
+		}
+		mu.Lock()
+		requests = append(requests, evs)
+		mu.Unlock()
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	s, err := newBetterStackSink(SinkConfig{
+		Type:     "betterstack",
+		Token:    "test-token",
+		Endpoint: server.URL,
+		MinLevel: LevelWarning,
+	})
+	if err != nil {
+		t.Fatalf("newBetterStackSink: %v", err)
+	}
+	defer s.Close()
+
+	s.WriteBatch([]Event{
+		{Level: LevelWarning, Iface: "Iface", Message: "warn"},
+		{Level: LevelInfo, Iface: "Iface", Message: "info"},
+		{Level: LevelError, Iface: "Iface", Message: "error"},
+	})
+	s.WriteBatch([]Event{{Level: LevelInfo, Iface: "Iface", Message: "info only"}})
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(requests) != 1 {
+		t.Fatalf("got %d requests, want 1 (a batch with nothing to send posts nothing)", len(requests))
+	}
+	if evs := requests[0]; len(evs) != 2 || evs[0].Message != "warn" || evs[1].Message != "error" {
+		t.Errorf("batch = %+v, want warn and error in order", evs)
+	}
+}
+
+func TestBetterStackSink_ReportsHTTPErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "invalid source token", http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	s, err := newBetterStackSink(SinkConfig{Type: "betterstack", Token: "bad-token", Endpoint: server.URL})
+	if err != nil {
+		t.Fatalf("newBetterStackSink: %v", err)
+	}
+	defer s.Close()
+
+	err = s.WriteErr(Event{Level: LevelError, Iface: "Iface", Message: "m"})
+	if err == nil || !strings.Contains(err.Error(), "401") || strings.Contains(err.Error(), "bad-token") {
+		t.Errorf("WriteErr = %v, want a 401 error without the token", err)
+	}
+	if err := s.WriteBatch([]Event{{Level: LevelError, Iface: "Iface", Message: "m"}}); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("WriteBatch = %v, want a 401 error", err)
+	}
+}
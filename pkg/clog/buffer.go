@@ -0,0 +1,145 @@
+// Package clog: pooled byte buffers and shared per-event renderings.
+package clog
+
+import (
+	"sync"
+	"sync/atomic"
+	"unsafe"
+)
+
+// maxPooledBuffer is the largest buffer returned to the pool; bigger ones
+// (a rare huge line) are left to the garbage collector instead of pinning
+// their memory.
+const maxPooledBuffer = 64 * 1024
+
+// buffer is a pooled byte slice for building one line.
+type buffer struct {
+	b []byte
+}
+
+var bufferPool = sync.Pool{
+	New: func() interface{} { return &buffer{b: make([]byte, 0, 512)} },
+}
+
+// getBuffer returns an empty buffer from the pool.
+func getBuffer() *buffer {
+	return bufferPool.Get().(*buffer)
+}
+
+// free returns b to the pool. b must not be used afterwards.
+func (b *buffer) free() {
+	if cap(b.b) > maxPooledBuffer {
+		return
+	}
+	b.b = b.b[:0]
+	bufferPool.Put(b)
+}
+
+// bytesString returns b as a string without copying. The string is only valid
+// while b is neither modified nor returned to the pool.
+func bytesString(b []byte) string {
+	return unsafe.String(unsafe.SliceData(b), len(b))
+}
+
+// lineFormat identifies an output format that built-in sinks share.
+type lineFormat uint8
+
+const (
+	// lineText is "[ts][LEVEL][facility]message k=v\n": the file sink and
+	// the console without colors.
+	lineText lineFormat = iota
+	// lineConsoleColor is the console's colored line with level emojis.
+	lineConsoleColor
+	// lineJSON is one JSON object without a trailing newline (BetterStack).
+	lineJSON
+	numLineFormats
+)
+
+// appendLine appends e rendered in format f to b.
+func appendLine(b []byte, e Event, f lineFormat) []byte {
+	switch f {
+	case lineConsoleColor:
+		return appendConsoleLine(b, e)
+	case lineJSON:
+		return appendJSONEvent(b, e)
+	default:
+		return appendTextLine(b, e, false)
+	}
+}
+
+// lineSink is implemented by built-in sinks whose output is one of the
+// shared line formats. Instead of formatting every event itself, such a sink
+// is handed the line the agent rendered once for all sinks using the format.
+type lineSink interface {
+	Sink
+	lineFormat() lineFormat
+	// writeLine writes one rendered line for an event at level from iface.
+	// line is only valid until writeLine returns.
+	writeLine(level Level, iface string, line []byte) error
+}
+
+// renderedSink is implemented by the delivery wrappers (asyncSink and
+// syncSink). lineFormat reports false unless the sink they wrap is a
+// lineSink; only then may writeRendered be called.
+type renderedSink interface {
+	lineFormat() (lineFormat, bool)
+	writeRendered(e Event, r *rendering)
+}
+
+// rendering holds the lines rendered for one event, one per format that some
+// sink needs. It is shared read-only by the sinks' workers and goes back to
+// the pool when the last of them releases it.
+type rendering struct {
+	refs  atomic.Int32
+	lines [numLineFormats]*buffer
+}
+
+var renderingPool = sync.Pool{
+	New: func() interface{} { return new(rendering) },
+}
+
+// newRendering returns an empty rendering holding one reference.
+func newRendering() *rendering {
+	r := renderingPool.Get().(*rendering)
+	r.refs.Store(1)
+	return r
+}
+
+// render renders e in format f unless that was done already. Only the
+// goroutine that created r may call it, before sharing r.
+func (r *rendering) render(e Event, f lineFormat) {
+	if r.lines[f] != nil {
+		return
+	}
+	buf := getBuffer()
+	buf.b = appendLine(buf.b, e, f)
+	r.lines[f] = buf
+}
+
+// line returns the line rendered in format f, or nil if it was not rendered
+// because no sink using f takes the event.
+func (r *rendering) line(f lineFormat) []byte {
+	if r.lines[f] == nil {
+		return nil
+	}
+	return r.lines[f].b
+}
+
+// retain adds a reference for a sink that writes r later.
+func (r *rendering) retain() {
+	r.refs.Add(1)
+}
+
+// release drops a reference; the last one returns the buffers to the pool.
+func (r *rendering) release() {
+	if r.refs.Add(-1) != 0 {
+		return
+	}
+	for i, buf := range r.lines {
+		if buf != nil {
+			buf.free()
+			r.lines[i] = nil
+		}
+	}
+	renderingPool.Put(r)
+}
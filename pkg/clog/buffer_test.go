@@ -0,0 +1,170 @@
+package clog
+
+import (
+	"context"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/LastBotInc/coralie-logging-go/internal/term"
+)
+
+// lineRecorder is a lineSink that keeps the lines it is handed.
+type lineRecorder struct {
+	format lineFormat
+	omit   Level
+
+	mu    sync.Mutex
+	lines [][]byte // the slices as passed, not copies
+	text  []string
+}
+
+func (s *lineRecorder) lineFormat() lineFormat { return s.format }
+
+func (s *lineRecorder) writeLine(level Level, _ string, line []byte) error {
+	if level == s.omit {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lines = append(s.lines, line)
+	s.text = append(s.text, string(line))
+	return nil
+}
+
+func (s *lineRecorder) Enabled(level Level, _ string) bool { return level != s.omit }
+func (s *lineRecorder) Write(level Level, iface, formatted string) {
+	buf := getBuffer()
+	defer buf.free()
+	buf.b = appendLine(buf.b, Event{Level: level, Iface: iface, Message: formatted}, s.format)
+	_ = s.writeLine(level, iface, buf.b)
+}
+func (s *lineRecorder) Flush() {}
+func (s *lineRecorder) Close() {}
+
+// newPipelineLogger returns a synchronous logger without hooks or dedupe
+// writing every level to a file and to a plain console that discards output.
+func newPipelineLogger(tb testing.TB) *Logger {
+	tb.Helper()
+	cfg := fileOnlyConfig(tb.TempDir(), nil)
+	cfg.Hooks.Global = nil
+	cfg.Synchronous = true
+	cfg.QueueSize = 0
+	cfg.Console.Enabled = true
+	cfg.Console.Colors = false
+	l, err := New(cfg)
+	if err != nil {
+		tb.Fatalf("New: %v", err)
+	}
+	tb.Cleanup(func() { l.Shutdown(context.Background()) })
+	for _, s := range l.agent.sinks {
+		if cs, ok := s.(*syncSink).inner.(*consoleSink); ok {
+			cs.out = io.Discard
+		}
+	}
+	return l
+}
+
+func TestBuiltinSinksAreLineSinks(t *testing.T) {
+	for _, s := range []Sink{&consoleSink{}, &fileSink{}, &betterstackSink{}} {
+		if _, ok := s.(lineSink); !ok {
+			t.Errorf("%T does not implement lineSink", s)
+		}
+	}
+}
+
+func TestWriteSinks_RendersEachFormatOnce(t *testing.T) {
+	l := newPipelineLogger(t)
+	text1 := &lineRecorder{format: lineText, omit: -1}
+	text2 := &lineRecorder{format: lineText, omit: -1}
+	color := &lineRecorder{format: lineConsoleColor, omit: -1}
+	quiet := &lineRecorder{format: lineJSON, omit: LevelInfo}
+	var c sinkCounters
+	l.agent.sinks = []Sink{
+		newSyncSink("text1", text1, DeliveryConfig{}, &c, nil),
+		newSyncSink("text2", text2, DeliveryConfig{}, &c, nil),
+		newSyncSink("color", color, DeliveryConfig{}, &c, nil),
+		newSyncSink("quiet", quiet, DeliveryConfig{}, &c, nil),
+	}
+
+	l.Info("Render", "hello %s", "world", Int("n", 1))
+
+	if len(text1.lines) != 1 || len(text2.lines) != 1 {
+		t.Fatalf("text sinks got %d and %d lines, want 1 each", len(text1.lines), len(text2.lines))
+	}
+	if &text1.lines[0][0] != &text2.lines[0][0] {
+		t.Error("text sinks were handed separately rendered lines, want one shared line")
+	}
+	if got := text1.text[0]; !strings.HasSuffix(got, "[INFO][Render]hello world n=1\n") {
+		t.Errorf("text line = %q", got)
+	}
+	if len(color.text) != 1 || !strings.Contains(color.text[0], term.ColorReset) ||
+		!strings.Contains(color.text[0], "hello world") {
+		t.Errorf("color lines = %q, want one colored line", color.text)
+	}
+	if len(quiet.lines) != 0 {
+		t.Errorf("sink omitting INFO got %q", quiet.text)
+	}
+	if got := c.written.Load(); got != 4 {
+		t.Errorf("written = %d, want 4 (a sink filtering the event still takes it)", got)
+	}
+}
+
+func TestProcessEvent_NoAllocationsForText(t *testing.T) {
+	if raceEnabled {
+		t.Skip("the race detector allocates")
+	}
+	l := newPipelineLogger(t)
+	e := Event{Level: LevelInfo, Iface: "Bench", Message: "request %s took %dms", Params: []interface{}{"GET", 12},
+		Fields: []Field{String("path", "/api/users"), Int("status", 200)}}
+	l.agent.processEvent(e) // warm the pools and open the file
+	if n := testing.AllocsPerRun(100, func() { l.agent.processEvent(e) }); n != 0 {
+		t.Errorf("processEvent allocated %v times per event, want 0", n)
+	}
+}
+
+func BenchmarkPipeline(b *testing.B) {
+	run := func(b *testing.B, l *Logger, e Event) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			l.agent.processEvent(e)
+		}
+	}
+	e := Event{Level: LevelInfo, Iface: "Bench", Message: "request %s took %dms", Params: []interface{}{"GET", 12}}
+
+	b.Run("Text", func(b *testing.B) {
+		run(b, newPipelineLogger(b), e)
+	})
+	b.Run("ConsoleColor", func(b *testing.B) {
+		l := newPipelineLogger(b)
+		for _, s := range l.agent.sinks {
+			if cs, ok := s.(*syncSink).inner.(*consoleSink); ok {
+				cs.useColor = true
+			}
+		}
+		run(b, l, e)
+	})
+	b.Run("Fields", func(b *testing.B) {
+		e := e
+		e.Fields = []Field{String("method", "GET"), Int("status", 200)}
+		run(b, newPipelineLogger(b), e)
+	})
+}
+
+func BenchmarkLogAsync(b *testing.B) {
+	cfg := fileOnlyConfig(b.TempDir(), nil)
+	cfg.Hooks.Global = nil
+	cfg.DropPolicy = "block"
+	l, err := New(cfg)
+	if err != nil {
+		b.Fatalf("New: %v", err)
+	}
+	defer l.Shutdown(context.Background())
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		l.Info("Bench", "request %s took %dms", "GET", 12)
+	}
+	l.Flush(context.Background())
+}
@@ -0,0 +1,75 @@
+// Package clog: caller location capture (file:line:function).
+package clog
+
+import (
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// CallerConfig configures caller location capture. When enabled, the API
+// records the program counter of the code that made the log call; the agent
+// resolves it to "dir/file.go:line:pkg.Func" in Event.Caller, which sinks
+// render as caller=... (text) or "caller" (JSON).
+type CallerConfig struct {
+	Enabled bool
+	// MinLevel limits capture to events at or above this level, so the cost is
+	// only paid where it matters (e.g. LevelWarning). LevelDebug = all levels.
+	MinLevel Level
+	// Skip is the number of extra stack frames to skip, for wrapper helpers
+	// that call clog on behalf of their own callers.
+	Skip int
+}
+
+// callerFramesToSkip is the number of frames between runtime.Callers and the
+// code that called the public API: runtime.Callers, capturePC,
+// Logger.logFields, Logger.log / Logger.logCtx, and the public function
+// itself. Every public logging entry point must keep this depth.
+const callerFramesToSkip = 5
+
+// capturePC returns the program counter of the log call site, or 0 when
+// caller capture is disabled for this level. Only the PC is taken on the
+// caller's goroutine; symbolization happens on the agent goroutine.
+func (c CallerConfig) capturePC(level Level) uintptr {
+	if !c.Enabled || !level.AtLeast(c.MinLevel) {
+		return 0
+	}
+	var pcs [1]uintptr
+	if runtime.Callers(callerFramesToSkip+c.Skip, pcs[:]) == 0 {
+		return 0
+	}
+	return pcs[0]
+}
+
+// formatCaller resolves a program counter (as returned by runtime.Callers)
+// to "dir/file.go:line:pkg.Func".
+func formatCaller(pc uintptr) string {
+	if pc == 0 {
+		return ""
+	}
+	frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	if frame.File == "" {
+		return ""
+	}
+	b := make([]byte, 0, 64)
+	b = append(b, trimPath(frame.File, 2)...)
+	b = append(b, ':')
+	b = strconv.AppendInt(b, int64(frame.Line), 10)
+	if frame.Function != "" {
+		b = append(b, ':')
+		b = append(b, trimPath(frame.Function, 1)...)
+	}
+	return string(b)
+}
+
+// trimPath keeps the last n slash-separated elements of p.
+func trimPath(p string, n int) string {
+	i := len(p)
+	for ; n > 0; n-- {
+		i = strings.LastIndexByte(p[:i], '/')
+		if i < 0 {
+			return p
+		}
+	}
+	return p[i+1:]
+}
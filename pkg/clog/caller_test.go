@@ -0,0 +1,127 @@
+package clog
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// here returns "clog/caller_test.go:<line>" for the line that calls it, offset
+// by delta lines.
+func here(delta int) string {
+	_, file, line, _ := runtime.Caller(1)
+	return trimPath(file, 2) + ":" + strconv.Itoa(line+delta)
+}
+
+// logViaHelper is a wrapper that logs on behalf of its caller (Caller.Skip = 1).
+func logViaHelper(l *Logger, msg string) {
+	l.Error("Test", msg)
+}
+
+func TestCaller_CapturedAtCallSite(t *testing.T) {
+	hook := &testHook{}
+	cfg := DefaultConfig()
+	cfg.Console.Enabled = false
+	cfg.Dedupe.Enabled = false
+	cfg.Hooks.Global = []Hook{hook}
+	cfg.Caller = CallerConfig{Enabled: true, MinLevel: LevelWarning}
+	l, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	var want []string
+	l.Warning("Test", "method")
+	want = append(want, here(-1))
+	l.Named("Sub").ErrorCtx(context.Background(), "X", "ctx method")
+	want = append(want, here(-1))
+	slog.New(NewSlogHandler(l, nil)).Error("via slog")
+	want = append(want, here(-1))
+	l.Info("Test", "below MinLevel")
+
+	l.Shutdown(context.Background())
+
+	events := hook.getEvents()
+	if len(events) != 4 {
+		t.Fatalf("got %d events, want 4", len(events))
+	}
+	for i, w := range want {
+		if !strings.HasPrefix(events[i].Caller, w+":") {
+			t.Errorf("event %d caller = %q, want prefix %q", i, events[i].Caller, w+":")
+		}
+		if !strings.HasSuffix(events[i].Caller, ":clog.TestCaller_CapturedAtCallSite") {
+			t.Errorf("event %d caller = %q, want test function name", i, events[i].Caller)
+		}
+	}
+	if events[3].Caller != "" {
+		t.Errorf("Info below MinLevel captured caller %q", events[3].Caller)
+	}
+}
+
+func TestCaller_PackageLevelAndSkip(t *testing.T) {
+	hook := &testHook{}
+	cfg := DefaultConfig()
+	cfg.Console.Enabled = false
+	cfg.Dedupe.Enabled = false
+	cfg.Hooks.Global = []Hook{hook}
+	cfg.Caller = CallerConfig{Enabled: true}
+	Init(cfg)
+	Info("Test", "package level")
+	wantPkg := here(-1)
+	Shutdown(context.Background())
+
+	cfg.Caller.Skip = 1
+	l, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	logViaHelper(l, "through helper")
+	wantSkip := here(-1)
+	l.Shutdown(context.Background())
+
+	events := hook.getEvents()
+	if len(events) != 2 {
+		t.Fatalf("got %d events, want 2", len(events))
+	}
+	if !strings.HasPrefix(events[0].Caller, wantPkg+":") {
+		t.Errorf("package-level caller = %q, want prefix %q", events[0].Caller, wantPkg)
+	}
+	if !strings.HasPrefix(events[1].Caller, wantSkip+":") {
+		t.Errorf("skipped caller = %q, want prefix %q", events[1].Caller, wantSkip)
+	}
+}
+
+func TestCaller_Rendering(t *testing.T) {
+	e := Event{Level: LevelError, Iface: "SIP", Message: "boom", Seq: 1,
+		Fields: []Field{String("caller", "shadow")}, Caller: "sip/t.go:7:sip.f"}
+
+	text := string(TextFormatter{}.FormatEvent(e))
+	if !strings.HasSuffix(text, "boom caller=shadow caller=sip/t.go:7:sip.f\n") {
+		t.Errorf("text = %q", text)
+	}
+	js := string(JSONFormatter{}.FormatEvent(e))
+	if !strings.Contains(js, `"seq":1,"caller":"sip/t.go:7:sip.f","fields.caller":"shadow"}`) {
+		t.Errorf("json = %s", js)
+	}
+}
+
+func TestTrimPath(t *testing.T) {
+	tests := []struct {
+		in   string
+		n    int
+		want string
+	}{
+		{"/src/pkg/clog/api.go", 2, "clog/api.go"},
+		{"clog/api.go", 2, "clog/api.go"},
+		{"api.go", 2, "api.go"},
+		{"github.com/x/y/pkg/sip.(*T).f", 1, "sip.(*T).f"},
+	}
+	for _, tt := range tests {
+		if got := trimPath(tt.in, tt.n); got != tt.want {
+			t.Errorf("trimPath(%q, %d) = %q, want %q", tt.in, tt.n, got, tt.want)
+		}
+	}
+}
@@ -1,42 +1,133 @@
 // Package clog: configuration structures.
 package clog
 
+import (
+	"io"
+	"time"
+)
+
 // Config holds the complete configuration for the logger.
 type Config struct {
-	QueueSize  int
+	QueueSize int
+	// DropPolicy decides what happens when the queue is full: "drop_new"
+	// (default, also used when empty), "drop_old" (evict the oldest queued
+	// event so the new one always gets in), "block" (wait up to BlockTimeout,
+	// then drop) or "block_forever". Unknown values are rejected.
 	DropPolicy string
-	Console    ConsoleConfig
-	File       FileConfig
-	Dedupe     DedupeConfig
-	Audio      AudioConfig
-	Hooks      HooksConfig
+	// BlockTimeout is the longest a log call waits for queue space under the
+	// "block" policy. Zero means 10ms.
+	BlockTimeout time.Duration
+	// PriorityReserve is extra queue capacity that only Error and Catastrophe
+	// events may use, so a flood of lower levels can never fill the queue
+	// ahead of them. Events are processed in the order they were queued,
+	// except under "drop_old": an Error or Catastrophe event (or a
+	// Reconfigure/Flush request) that a lower-level event may not evict is
+	// moved from the head of the queue to its tail, behind newer events.
+	// DefaultConfig reserves 100 slots; zero disables the reserve.
+	PriorityReserve int
+	// ShedThresholds maps a level to the queue fill ratio (0 < r <= 1, of
+	// QueueSize) at which new events of that level are refused, so Debug
+	// goes first, then Info, then Success. Levels without an entry are never
+	// shed. Nil (the default) sheds nothing; DefaultShedThresholds is a
+	// ready-made ladder. Shedding never applies under the "block" and
+	// "block_forever" policies, which exist so that events wait rather than
+	// drop.
+	ShedThresholds map[Level]float64
+	Console        ConsoleConfig
+	File           FileConfig
+	Dedupe         DedupeConfig
+	Audio          AudioConfig
+	Hooks          HooksConfig
 	// Sinks configures additional third-party sinks (e.g. BetterStack). Nil = no extra sinks.
 	Sinks []SinkConfig
+	// ContextExtractors pull correlation fields (trace/span IDs, request ID,
+	// call ID, ...) out of the context passed to the *Ctx logging functions
+	// and the slog handler. DefaultConfig registers DefaultContextExtractors.
+	ContextExtractors []ContextExtractor
+	// Caller enables call-site capture (file:line:function) per level.
+	Caller CallerConfig
+	// FacilityLevels sets minimum levels per facility ("SIP.*": LevelDebug,
+	// "RTP": LevelWarning, "*": LevelInfo). Events below their facility's
+	// minimum are discarded in the log call, before enqueue. Nil = all levels.
+	FacilityLevels FacilityLevels
+	// Redaction adds PII redaction patterns for this logger.
+	Redaction RedactionConfig
+	// Synchronous runs the whole pipeline (format, dedupe, redact, hooks,
+	// sinks) on the logging goroutine under a lock, with no queue, agent
+	// goroutine or per-sink workers: output is written before a log call
+	// returns. Meant for tests and short-lived CLIs. QueueSize, DropPolicy,
+	// PriorityReserve, ShedThresholds and the Delivery buffer settings do not
+	// apply. Hooks must not log through the same logger (it would deadlock).
+	Synchronous bool
+	// OnSinkError is called for every failed sink write, flush or close,
+	// which is also counted in SinkStats.Errors. It runs on the failing
+	// sink's worker goroutine (the logging goroutine in synchronous mode), so
+	// it must return quickly; in synchronous mode it must not log through the
+	// same logger (it would deadlock). Nil = errors are only counted and
+	// reported on DiagnosticsOutput.
+	OnSinkError func(err *SinkError)
+	// DiagnosticsOutput receives the logger's own fault reports, such as
+	// failing sinks: one line per fault, repeats at most every 10s. They never
+	// go through a sink, so a broken sink cannot feed on its own errors. Nil
+	// = os.Stderr; io.Discard silences them.
+	DiagnosticsOutput io.Writer
+	// MaxConsecutivePanics is how many panics in a row quarantine a hook or
+	// sink: it is no longer called (a sink is still closed) until the next
+	// Reconfigure, and a Catastrophe event (facility "System") reports it.
+	// Every panic is recovered and counted in Stats. Zero means 3.
+	MaxConsecutivePanics int
 }
 
 // SinkConfig configures one additional sink. Type determines which sink to use ("betterstack", etc.).
-// MinLevel and OmitLevels apply level filtering for this sink. Format is "text" or "json".
-// Type-specific fields: for Type "betterstack", set Token and optionally Endpoint.
+// MinLevel and OmitLevels apply level filtering for this sink, whatever its type.
+// Format is "text" or "json" and is only accepted for Type "betterstack".
+// Type-specific fields: for Type "betterstack", set Token and optionally Endpoint;
+// sink types added with RegisterSinkType read theirs from Options.
+// Name identifies the sink in Stats.Sinks (default: Type). Delivery configures
+// its buffer and worker.
+//
+// Instance supplies a ready-made Sink instead (Type is then only the default
+// name). The logger writes and flushes it but never closes it; the caller
+// closes it after Shutdown, and may pass it to Reconfigure again.
 type SinkConfig struct {
-	Type       string       // "betterstack", etc.
-	MinLevel   Level        // only emit events at or above this level; LevelDebug = all
+	Type       string // "betterstack", or a type added with RegisterSinkType
+	Name       string
+	MinLevel   Level // only emit events at or above this level; LevelDebug = all
 	OmitLevels map[Level]bool
-	Format     string       // "text" or "json"
-	Token      string       // for betterstack: source token
-	Endpoint   string       // for betterstack: ingest URL (default https://in.logs.betterstack.com)
+	Format     string        // "text" or "json"
+	Token      string        // for betterstack: source token
+	Endpoint   string        // for betterstack: ingest URL (default https://in.logs.betterstack.com)
+	Timeout    time.Duration // for betterstack: HTTP request timeout (default 10s)
+	Options    map[string]string
+	Instance   Sink
+	Delivery   DeliveryConfig
+}
+
+// name returns the sink's name in Stats.Sinks before de-duplication.
+func (c SinkConfig) name() string {
+	switch {
+	case c.Name != "":
+		return c.Name
+	case c.Type != "":
+		return c.Type
+	default:
+		return "instance"
+	}
 }
 
 // ConsoleConfig configures console output.
 type ConsoleConfig struct {
-	Enabled   bool
-	Colors    bool
+	Enabled    bool
+	Colors     bool
 	OmitLevels map[Level]bool
+	Delivery   DeliveryConfig
 }
 
 // FileConfig configures file output.
 type FileConfig struct {
 	BaseDir  string
 	PerLevel map[Level]string
+	Delivery DeliveryConfig
 }
 
 // DedupeConfig configures deduplication.
@@ -50,11 +141,22 @@ type AudioConfig struct {
 	Enabled         bool
 	SampleRate      int
 	Channels        int
-	BitsPerSample   int
+	BitsPerSample   int // only 16 is supported; 0 means 16
 	OutputDir       string
 	FilenamePattern string
 }
 
+// RedactionConfig adds redaction rules to one logger. Whether redaction runs
+// at all is still decided globally (CORALIE_LOG_REDACT, SetRedactionEnabled).
+type RedactionConfig struct {
+	// Patterns are applied in order after the package-level redactor (the
+	// default PII set, or whatever SetRedactor installed).
+	Patterns []RedactPattern
+	// ReplaceDefaults applies Patterns alone, skipping the package-level
+	// redactor.
+	ReplaceDefaults bool
+}
+
 // HooksConfig configures hooks.
 type HooksConfig struct {
 	Global   []Hook
@@ -64,8 +166,9 @@ type HooksConfig struct {
 // DefaultConfig returns a default configuration.
 func DefaultConfig() Config {
 	return Config{
-		QueueSize:  1000,
-		DropPolicy: "drop_new",
+		QueueSize:       1000,
+		PriorityReserve: 100,
+		DropPolicy:      "drop_new",
 		Console: ConsoleConfig{
 			Enabled: true,
 		},
@@ -73,10 +176,6 @@ func DefaultConfig() Config {
 			Enabled:       true,
 			SummaryFormat: "last message repeated %d more times",
 		},
+		ContextExtractors: DefaultContextExtractors(),
 	}
 }
-
-
-
-
-
@@ -0,0 +1,143 @@
+// Package clog: CORALIE_LOG_* environment overlay for Config.
+package clog
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// envPrefix is the prefix of every environment variable ApplyEnv reads.
+const envPrefix = "CORALIE_LOG_"
+
+// envSetting is one CORALIE_LOG_* variable and how it changes a Config.
+type envSetting struct {
+	name  string // without envPrefix
+	apply func(cfg *Config, v string) error
+}
+
+// envSettings are applied in this order, so CORALIE_LOG_LEVEL overrides the
+// "*" entry of CORALIE_LOG_FACILITY_LEVELS and CORALIE_LOG_BETTERSTACK_ENDPOINT
+// applies to the sink CORALIE_LOG_BETTERSTACK_TOKEN may have added.
+var envSettings = []envSetting{
+	{"QUEUE_SIZE", func(c *Config, v string) error { return envInt(v, &c.QueueSize) }},
+	{"DROP_POLICY", func(c *Config, v string) error { c.DropPolicy = v; return nil }},
+	{"BLOCK_TIMEOUT", func(c *Config, v string) error { return envDuration(v, &c.BlockTimeout) }},
+	{"PRIORITY_RESERVE", func(c *Config, v string) error { return envInt(v, &c.PriorityReserve) }},
+	{"SYNCHRONOUS", func(c *Config, v string) error { return envBool(v, &c.Synchronous) }},
+	{"CONSOLE", func(c *Config, v string) error { return envBool(v, &c.Console.Enabled) }},
+	{"CONSOLE_COLORS", func(c *Config, v string) error { return envBool(v, &c.Console.Colors) }},
+	{"FILE_DIR", func(c *Config, v string) error { c.File.BaseDir = v; return nil }},
+	{"DEDUPE", func(c *Config, v string) error { return envBool(v, &c.Dedupe.Enabled) }},
+	{"CALLER", func(c *Config, v string) error { return envBool(v, &c.Caller.Enabled) }},
+	{"AUDIO", func(c *Config, v string) error { return envBool(v, &c.Audio.Enabled) }},
+	{"AUDIO_DIR", func(c *Config, v string) error { c.Audio.OutputDir = v; return nil }},
+	{"FACILITY_LEVELS", func(c *Config, v string) error {
+		levels, err := ParseFacilityLevels(v)
+		if err != nil {
+			return err
+		}
+		c.FacilityLevels = levels
+		return nil
+	}},
+	{"LEVEL", func(c *Config, v string) error {
+		level, err := ParseLevel(v)
+		if err != nil {
+			return err
+		}
+		levels := make(FacilityLevels, len(c.FacilityLevels)+1)
+		for pattern, l := range c.FacilityLevels {
+			levels[pattern] = l
+		}
+		levels["*"] = level
+		c.FacilityLevels = levels
+		return nil
+	}},
+	{"BETTERSTACK_TOKEN", func(c *Config, v string) error {
+		c.Sinks = append([]SinkConfig(nil), c.Sinks...)
+		found := false
+		for i := range c.Sinks {
+			if c.Sinks[i].Type == "betterstack" {
+				c.Sinks[i].Token = v
+				found = true
+			}
+		}
+		if !found {
+			c.Sinks = append(c.Sinks, SinkConfig{Type: "betterstack", Format: "json", Token: v})
+		}
+		return nil
+	}},
+	{"BETTERSTACK_ENDPOINT", func(c *Config, v string) error {
+		c.Sinks = append([]SinkConfig(nil), c.Sinks...)
+		for i := range c.Sinks {
+			if c.Sinks[i].Type == "betterstack" {
+				c.Sinks[i].Endpoint = v
+			}
+		}
+		return nil
+	}},
+}
+
+// ApplyEnv overlays the CORALIE_LOG_* environment variables that are set
+// (and not empty) onto cfg; LoadConfig calls it after reading the file.
+// Every bad value is reported, joined, as a *ConfigError naming the variable;
+// cfg keeps the good ones. Maps and slices are copied before they change, so
+// a Config sharing them is not affected. CORALIE_LOG_REDACT is not part of
+// the overlay: it toggles redaction process-wide and is read at startup.
+func ApplyEnv(cfg *Config) error {
+	var errs []error
+	for _, s := range envSettings {
+		name := envPrefix + s.name
+		v := strings.TrimSpace(os.Getenv(name))
+		if v == "" {
+			continue
+		}
+		if err := s.apply(cfg, v); err != nil {
+			errs = append(errs, &ConfigError{Field: name, Err: err})
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// parseEnvBool parses the boolean spellings accepted in CORALIE_LOG_*
+// variables: 1/true/yes/on and 0/false/no/off, case-insensitive.
+func parseEnvBool(v string) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(v)) {
+	case "1", "true", "yes", "on":
+		return true, nil
+	case "0", "false", "no", "off":
+		return false, nil
+	default:
+		return false, fmt.Errorf("want a boolean (true/false, 1/0, yes/no, on/off), got %q", v)
+	}
+}
+
+func envBool(v string, dst *bool) error {
+	b, err := parseEnvBool(v)
+	if err != nil {
+		return err
+	}
+	*dst = b
+	return nil
+}
+
+func envInt(v string, dst *int) error {
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return fmt.Errorf("want an integer, got %q", v)
+	}
+	*dst = n
+	return nil
+}
+
+func envDuration(v string, dst *time.Duration) error {
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return err
+	}
+	*dst = d
+	return nil
+}
@@ -0,0 +1,299 @@
+// Package clog: loading Config from JSON and YAML files.
+package clog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/LastBotInc/coralie-logging-go/internal/yamlite"
+)
+
+// LoadConfig builds a Config from, in increasing precedence: DefaultConfig,
+// the file at path (skipped when path is empty), and the CORALIE_LOG_*
+// environment variables (see ApplyEnv). Files ending in .json are JSON;
+// anything else is read as YAML (the subset documented in CONFIGURATION.md).
+// Keys are snake_case field names, levels are given by name, and unknown keys
+// are rejected. Hooks, context extractors, sink instances, OnSinkError and
+// DiagnosticsOutput cannot come from a file; set them on the returned Config.
+// The result is not validated until it is passed to Init, InitE, New or
+// Reconfigure (or Config.Validate).
+func LoadConfig(path string) (Config, error) {
+	cfg := DefaultConfig()
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return Config{}, err
+		}
+		if err := decodeConfigFile(data, strings.EqualFold(filepath.Ext(path), ".json"), &cfg); err != nil {
+			return Config{}, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	if err := ApplyEnv(&cfg); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+// decodeConfigFile overlays the settings present in a config file onto cfg.
+func decodeConfigFile(data []byte, isJSON bool, cfg *Config) error {
+	if !isJSON {
+		tree, err := yamlite.Parse(data)
+		if err != nil {
+			return err
+		}
+		if tree == nil {
+			return nil
+		}
+		if data, err = json.Marshal(tree); err != nil {
+			return err
+		}
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	var fc docConfig
+	if err := dec.Decode(&fc); err != nil {
+		return err
+	}
+	return fc.apply(cfg)
+}
+
+// docConfig is the config file schema. Pointer and nil-able fields stay nil
+// when absent from the file, so only the settings present override cfg.
+type docConfig struct {
+	QueueSize       *int               `json:"queue_size"`
+	DropPolicy      *string            `json:"drop_policy"`
+	BlockTimeout    *docDuration       `json:"block_timeout"`
+	PriorityReserve *int               `json:"priority_reserve"`
+	ShedThresholds  map[string]float64 `json:"shed_thresholds"`
+	Synchronous     *bool              `json:"synchronous"`
+	Console         *docConsole        `json:"console"`
+	File            *docFile           `json:"file"`
+	Dedupe          *docDedupe         `json:"dedupe"`
+	Audio           *docAudio          `json:"audio"`
+	Sinks           []docSink          `json:"sinks"`
+	Caller          *docCaller         `json:"caller"`
+	FacilityLevels  FacilityLevels     `json:"facility_levels"`
+	Redaction       *docRedaction      `json:"redaction"`
+	MaxPanics       *int               `json:"max_consecutive_panics"`
+}
+
+type docConsole struct {
+	Enabled    *bool        `json:"enabled"`
+	Colors     *bool        `json:"colors"`
+	OmitLevels []Level      `json:"omit_levels"`
+	Delivery   *docDelivery `json:"delivery"`
+}
+
+type docFile struct {
+	BaseDir  *string           `json:"base_dir"`
+	PerLevel map[string]string `json:"per_level"`
+	Delivery *docDelivery      `json:"delivery"`
+}
+
+type docDedupe struct {
+	Enabled       *bool   `json:"enabled"`
+	SummaryFormat *string `json:"summary_format"`
+}
+
+type docAudio struct {
+	Enabled         *bool   `json:"enabled"`
+	SampleRate      *int    `json:"sample_rate"`
+	Channels        *int    `json:"channels"`
+	BitsPerSample   *int    `json:"bits_per_sample"`
+	OutputDir       *string `json:"output_dir"`
+	FilenamePattern *string `json:"filename_pattern"`
+}
+
+type docSink struct {
+	Type       string            `json:"type"`
+	Name       string            `json:"name"`
+	MinLevel   Level             `json:"min_level"`
+	OmitLevels []Level           `json:"omit_levels"`
+	Format     string            `json:"format"`
+	Token      string            `json:"token"`
+	Endpoint   string            `json:"endpoint"`
+	Timeout    docDuration       `json:"timeout"`
+	Options    map[string]string `json:"options"`
+	Delivery   *docDelivery      `json:"delivery"`
+}
+
+type docDelivery struct {
+	BufferSize         int         `json:"buffer_size"`
+	DropPolicy         string      `json:"drop_policy"`
+	BlockTimeout       docDuration `json:"block_timeout"`
+	SlowWriteThreshold docDuration `json:"slow_write_threshold"`
+	MaxBatchSize       int         `json:"max_batch_size"`
+	MaxBatchLinger     docDuration `json:"max_batch_linger"`
+}
+
+type docCaller struct {
+	Enabled  *bool  `json:"enabled"`
+	MinLevel *Level `json:"min_level"`
+	Skip     *int   `json:"skip"`
+}
+
+type docRedaction struct {
+	ReplaceDefaults bool               `json:"replace_defaults"`
+	Patterns        []docRedactPattern `json:"patterns"`
+}
+
+type docRedactPattern struct {
+	Name        string `json:"name"`
+	Regex       string `json:"regex"`
+	Replacement string `json:"replacement"`
+}
+
+// docDuration is a time.Duration written as a string ("10ms", "2s").
+type docDuration time.Duration
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (d *docDuration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("duration must be a string such as \"10ms\", got %s", b)
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = docDuration(v)
+	return nil
+}
+
+// apply overlays the settings present in fc onto cfg.
+func (fc *docConfig) apply(cfg *Config) error {
+	var errs []error
+	setIf(&cfg.QueueSize, fc.QueueSize)
+	setIf(&cfg.DropPolicy, fc.DropPolicy)
+	if fc.BlockTimeout != nil {
+		cfg.BlockTimeout = time.Duration(*fc.BlockTimeout)
+	}
+	setIf(&cfg.PriorityReserve, fc.PriorityReserve)
+	if fc.ShedThresholds != nil {
+		cfg.ShedThresholds = make(map[Level]float64, len(fc.ShedThresholds))
+		for name, ratio := range fc.ShedThresholds {
+			level, err := ParseLevel(name)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("shed_thresholds: %w", err))
+				continue
+			}
+			cfg.ShedThresholds[level] = ratio
+		}
+	}
+	setIf(&cfg.Synchronous, fc.Synchronous)
+	setIf(&cfg.MaxConsecutivePanics, fc.MaxPanics)
+
+	if c := fc.Console; c != nil {
+		setIf(&cfg.Console.Enabled, c.Enabled)
+		setIf(&cfg.Console.Colors, c.Colors)
+		if c.OmitLevels != nil {
+			cfg.Console.OmitLevels = levelSet(c.OmitLevels)
+		}
+		if c.Delivery != nil {
+			cfg.Console.Delivery = c.Delivery.config()
+		}
+	}
+	if f := fc.File; f != nil {
+		setIf(&cfg.File.BaseDir, f.BaseDir)
+		if f.PerLevel != nil {
+			cfg.File.PerLevel = make(map[Level]string, len(f.PerLevel))
+			for name, file := range f.PerLevel {
+				level, err := ParseLevel(name)
+				if err != nil {
+					errs = append(errs, fmt.Errorf("file.per_level: %w", err))
+					continue
+				}
+				cfg.File.PerLevel[level] = file
+			}
+		}
+		if f.Delivery != nil {
+			cfg.File.Delivery = f.Delivery.config()
+		}
+	}
+	if d := fc.Dedupe; d != nil {
+		setIf(&cfg.Dedupe.Enabled, d.Enabled)
+		setIf(&cfg.Dedupe.SummaryFormat, d.SummaryFormat)
+	}
+	if a := fc.Audio; a != nil {
+		setIf(&cfg.Audio.Enabled, a.Enabled)
+		setIf(&cfg.Audio.SampleRate, a.SampleRate)
+		setIf(&cfg.Audio.Channels, a.Channels)
+		setIf(&cfg.Audio.BitsPerSample, a.BitsPerSample)
+		setIf(&cfg.Audio.OutputDir, a.OutputDir)
+		setIf(&cfg.Audio.FilenamePattern, a.FilenamePattern)
+	}
+	if fc.Sinks != nil {
+		cfg.Sinks = make([]SinkConfig, 0, len(fc.Sinks))
+		for _, s := range fc.Sinks {
+			sc := SinkConfig{
+				Type:     s.Type,
+				Name:     s.Name,
+				MinLevel: s.MinLevel,
+				Format:   s.Format,
+				Token:    s.Token,
+				Endpoint: s.Endpoint,
+				Timeout:  time.Duration(s.Timeout),
+				Options:  s.Options,
+			}
+			if s.OmitLevels != nil {
+				sc.OmitLevels = levelSet(s.OmitLevels)
+			}
+			if s.Delivery != nil {
+				sc.Delivery = s.Delivery.config()
+			}
+			cfg.Sinks = append(cfg.Sinks, sc)
+		}
+	}
+	if c := fc.Caller; c != nil {
+		setIf(&cfg.Caller.Enabled, c.Enabled)
+		if c.MinLevel != nil {
+			cfg.Caller.MinLevel = *c.MinLevel
+		}
+		setIf(&cfg.Caller.Skip, c.Skip)
+	}
+	if fc.FacilityLevels != nil {
+		cfg.FacilityLevels = fc.FacilityLevels
+	}
+	if r := fc.Redaction; r != nil {
+		cfg.Redaction.ReplaceDefaults = r.ReplaceDefaults
+		cfg.Redaction.Patterns = nil
+		for _, p := range r.Patterns {
+			cfg.Redaction.Patterns = append(cfg.Redaction.Patterns, RedactPattern(p))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// config converts a file delivery section.
+func (d *docDelivery) config() DeliveryConfig {
+	return DeliveryConfig{
+		BufferSize:         d.BufferSize,
+		DropPolicy:         d.DropPolicy,
+		BlockTimeout:       time.Duration(d.BlockTimeout),
+		SlowWriteThreshold: time.Duration(d.SlowWriteThreshold),
+		MaxBatchSize:       d.MaxBatchSize,
+		MaxBatchLinger:     time.Duration(d.MaxBatchLinger),
+	}
+}
+
+// setIf sets *dst to *v when the file set v.
+func setIf[T any](dst *T, v *T) {
+	if v != nil {
+		*dst = *v
+	}
+}
+
+// levelSet turns a list of levels into an OmitLevels set.
+func levelSet(levels []Level) map[Level]bool {
+	out := make(map[Level]bool, len(levels))
+	for _, l := range levels {
+		out[l] = true
+	}
+	return out
+}
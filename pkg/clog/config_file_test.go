@@ -0,0 +1,235 @@
+package clog
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testYAMLConfig = `
+queue_size: 500
+drop_policy: block
+block_timeout: 25ms
+shed_thresholds: {debug: 0.4}
+max_consecutive_panics: 5
+console:
+  enabled: false
+  omit_levels: [debug]
+file:
+  base_dir: /var/log/app
+  per_level:
+    error: errors.log
+    warn: warnings.log
+dedupe:
+  enabled: false
+audio:
+  enabled: true
+  sample_rate: 16000
+  channels: 1
+  output_dir: /var/log/audio
+sinks:
+  - type: betterstack
+    token: tok
+    min_level: warning
+    format: json
+    timeout: 3s
+    delivery:
+      buffer_size: 64
+      slow_write_threshold: 5s
+      max_batch_size: 50
+      max_batch_linger: 250ms
+  - type: kafka
+    options: {topic: logs}
+caller:
+  enabled: true
+  min_level: error
+facility_levels:
+  "SIP.*": debug
+  "*": info
+redaction:
+  patterns:
+    - name: secret
+      regex: 'secret=\S+'
+      replacement: secret=<redacted>
+`
+
+const testJSONConfig = `{
+  "queue_size": 500,
+  "drop_policy": "block",
+  "block_timeout": "25ms",
+  "shed_thresholds": {"debug": 0.4},
+  "max_consecutive_panics": 5,
+  "console": {"enabled": false, "omit_levels": ["debug"]},
+  "file": {"base_dir": "/var/log/app", "per_level": {"error": "errors.log", "warn": "warnings.log"}},
+  "dedupe": {"enabled": false},
+  "audio": {"enabled": true, "sample_rate": 16000, "channels": 1, "output_dir": "/var/log/audio"},
+  "sinks": [
+    {"type": "betterstack", "token": "tok", "min_level": "warning", "format": "json", "timeout": "3s",
+     "delivery": {"buffer_size": 64, "slow_write_threshold": "5s", "max_batch_size": 50, "max_batch_linger": "250ms"}},
+    {"type": "kafka", "options": {"topic": "logs"}}
+  ],
+  "caller": {"enabled": true, "min_level": "error"},
+  "facility_levels": {"SIP.*": "debug", "*": "info"},
+  "redaction": {"patterns": [{"name": "secret", "regex": "secret=\\S+", "replacement": "secret=<redacted>"}]}
+}`
+
+func writeConfigFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadConfig_YAMLAndJSON(t *testing.T) {
+	want := DefaultConfig()
+	want.QueueSize = 500
+	want.DropPolicy = "block"
+	want.BlockTimeout = 25 * time.Millisecond
+	want.ShedThresholds = map[Level]float64{LevelDebug: 0.4}
+	want.MaxConsecutivePanics = 5
+	want.Console.Enabled = false
+	want.Console.OmitLevels = map[Level]bool{LevelDebug: true}
+	want.File = FileConfig{BaseDir: "/var/log/app", PerLevel: map[Level]string{LevelError: "errors.log", LevelWarning: "warnings.log"}}
+	want.Dedupe.Enabled = false
+	want.Audio = AudioConfig{Enabled: true, SampleRate: 16000, Channels: 1, OutputDir: "/var/log/audio"}
+	want.Sinks = []SinkConfig{
+		{Type: "betterstack", Token: "tok", MinLevel: LevelWarning, Format: "json", Timeout: 3 * time.Second,
+			Delivery: DeliveryConfig{BufferSize: 64, SlowWriteThreshold: 5 * time.Second, MaxBatchSize: 50, MaxBatchLinger: 250 * time.Millisecond}},
+		{Type: "kafka", Options: map[string]string{"topic": "logs"}},
+	}
+	want.Caller = CallerConfig{Enabled: true, MinLevel: LevelError}
+	want.FacilityLevels = FacilityLevels{"SIP.*": LevelDebug, "*": LevelInfo}
+	want.Redaction.Patterns = []RedactPattern{{Name: "secret", Regex: `secret=\S+`, Replacement: "secret=<redacted>"}}
+	want.ContextExtractors = nil
+
+	for _, tt := range []struct{ name, content string }{
+		{"logging.yaml", testYAMLConfig},
+		{"logging.json", testJSONConfig},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := LoadConfig(writeConfigFile(t, tt.name, tt.content))
+			if err != nil {
+				t.Fatalf("LoadConfig: %v", err)
+			}
+			// Functions cannot be compared; the defaults are kept.
+			if len(got.ContextExtractors) != len(DefaultContextExtractors()) {
+				t.Errorf("ContextExtractors = %d, want the defaults", len(got.ContextExtractors))
+			}
+			got.ContextExtractors = nil
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("LoadConfig =\n%+v\nwant\n%+v", got, want)
+			}
+		})
+	}
+}
+
+func TestLoadConfig_Errors(t *testing.T) {
+	tests := []struct {
+		name, file, content, want string
+	}{
+		{"unknown_key", "c.yaml", "queue_sise: 10", `unknown field "queue_sise"`},
+		{"bad_level", "c.yaml", "caller: {min_level: loud}", `unknown level "loud"`},
+		{"bad_level_key", "c.json", `{"file": {"per_level": {"trace": "t.log"}}}`, `file.per_level: unknown level "trace"`},
+		{"bad_duration", "c.yaml", "block_timeout: 10", "duration must be a string"},
+		{"bad_yaml", "c.yml", "console:\n  enabled: [", "line 2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeConfigFile(t, tt.file, tt.content)
+			_, err := LoadConfig(path)
+			if err == nil || !strings.Contains(err.Error(), tt.want) || !strings.Contains(err.Error(), path) {
+				t.Errorf("err = %v, want %q with the path", err, tt.want)
+			}
+		})
+	}
+	if _, err := LoadConfig(filepath.Join(t.TempDir(), "missing.yaml")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("missing file: err = %v", err)
+	}
+}
+
+func TestLoadConfig_EnvOverridesFile(t *testing.T) {
+	path := writeConfigFile(t, "c.yaml", "queue_size: 500\nfacility_levels: {SIP: debug, '*': warning}\n")
+	t.Setenv("CORALIE_LOG_QUEUE_SIZE", "700")
+	t.Setenv("CORALIE_LOG_LEVEL", "error")
+	t.Setenv("CORALIE_LOG_CONSOLE", "off")
+	t.Setenv("CORALIE_LOG_BLOCK_TIMEOUT", "50ms")
+	t.Setenv("CORALIE_LOG_BETTERSTACK_TOKEN", "env-token")
+	t.Setenv("CORALIE_LOG_BETTERSTACK_ENDPOINT", "https://eu.example.com")
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if cfg.QueueSize != 700 {
+		t.Errorf("QueueSize = %d, want the env value 700", cfg.QueueSize)
+	}
+	if want := (FacilityLevels{"SIP": LevelDebug, "*": LevelError}); !reflect.DeepEqual(cfg.FacilityLevels, want) {
+		t.Errorf("FacilityLevels = %v, want %v", cfg.FacilityLevels, want)
+	}
+	if cfg.Console.Enabled || cfg.BlockTimeout != 50*time.Millisecond {
+		t.Errorf("Console.Enabled = %v, BlockTimeout = %v", cfg.Console.Enabled, cfg.BlockTimeout)
+	}
+	want := []SinkConfig{{Type: "betterstack", Format: "json", Token: "env-token", Endpoint: "https://eu.example.com"}}
+	if !reflect.DeepEqual(cfg.Sinks, want) {
+		t.Errorf("Sinks = %+v, want %+v", cfg.Sinks, want)
+	}
+}
+
+func TestApplyEnv_ReportsEveryBadVariable(t *testing.T) {
+	t.Setenv("CORALIE_LOG_QUEUE_SIZE", "lots")
+	t.Setenv("CORALIE_LOG_DEDUPE", "maybe")
+	t.Setenv("CORALIE_LOG_LEVEL", "warn")
+
+	cfg := DefaultConfig()
+	err := ApplyEnv(&cfg)
+	var ce *ConfigError
+	if !errors.As(err, &ce) {
+		t.Fatalf("err = %v, want *ConfigError", err)
+	}
+	for _, name := range []string{"CORALIE_LOG_QUEUE_SIZE", "CORALIE_LOG_DEDUPE"} {
+		if !strings.Contains(err.Error(), name) {
+			t.Errorf("err = %v, missing %s", err, name)
+		}
+	}
+	if cfg.FacilityLevels["*"] != LevelWarning {
+		t.Errorf("valid CORALIE_LOG_LEVEL not applied: %v", cfg.FacilityLevels)
+	}
+}
+
+func TestRedactionConfig_AddsPatterns(t *testing.T) {
+	hook := &testHook{}
+	cfg := DefaultConfig()
+	cfg.Console.Enabled = false
+	cfg.Synchronous = true
+	cfg.Hooks.Global = []Hook{hook}
+	cfg.Redaction.Patterns = []RedactPattern{{Name: "secret", Regex: `secret=\S+`, Replacement: "secret=<redacted>"}}
+	l, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	l.Info("Test", "login secret=hunter2 from alice@example.com", String("auth", "secret=abc"))
+	l.Shutdown(context.Background())
+
+	events := hook.getEvents()
+	if len(events) != 1 {
+		t.Fatalf("got %d events", len(events))
+	}
+	if got := events[0].Message; got != "login secret=<redacted> from <email>" {
+		t.Errorf("Message = %q", got)
+	}
+	if got := fieldMap(events[0])["auth"]; got != "secret=<redacted>" {
+		t.Errorf("field = %q", got)
+	}
+
+	cfg.Redaction.Patterns[0].Regex = "("
+	if _, err := New(cfg); err == nil || !strings.Contains(err.Error(), "Redaction.Patterns[0]") {
+		t.Errorf("bad pattern: err = %v", err)
+	}
+}
@@ -0,0 +1,262 @@
+// Package clog: hot reload of a running logger from a watched config file.
+package clog
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultWatchInterval is the polling interval WatchConfig uses when given
+// an interval <= 0.
+const DefaultWatchInterval = 2 * time.Second
+
+// watchIface is the facility of the events a ConfigWatcher logs.
+const watchIface = "Config"
+
+// ConfigWatcher polls a config file and applies its changes to a running
+// Logger. Create one with WatchConfig and stop it with Stop.
+type ConfigWatcher struct {
+	logger   *Logger
+	path     string
+	interval time.Duration
+
+	// Owned by the polling goroutine after WatchConfig returns. sum is the
+	// content last applied; pending is a changed content seen on the last
+	// poll, applied if the next poll finds the file unchanged.
+	modTime  time.Time
+	size     int64
+	sum      [sha256.Size]byte
+	pending  [sha256.Size]byte
+	settling bool
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	exited   chan struct{}
+}
+
+// WatchConfig watches path for the default logger. See Logger.WatchConfig.
+func WatchConfig(path string, interval time.Duration) (*ConfigWatcher, error) {
+	return Default().WatchConfig(path, interval)
+}
+
+// WatchConfig starts a goroutine that checks the config file at path every
+// interval (DefaultWatchInterval if interval <= 0) and reloads it when its
+// modification time or size changed and its content hash differs from the
+// last one applied. A change is only applied once the file has stayed the
+// same for one more poll, so a file caught half-written is not loaded;
+// writing a temporary file and renaming it over path is still the safest
+// way to edit it. The current content is the baseline: the logger is assumed
+// to be running with it already, typically from LoadConfig(path).
+//
+// A reload reads the file with LoadConfig, so the CORALIE_LOG_* variables
+// still override it, and keeps what a file cannot express from the running
+// Config: hooks, context extractors, OnSinkError, DiagnosticsOutput and sinks
+// given as SinkConfig.Instance.
+// If nothing changed, nothing happens. Otherwise only the changed parts are
+// applied, as by Reconfigure: sinks whose settings did not change keep
+// running (with their open files, buffers and quarantine), hooks are kept,
+// and the dedupe state, redaction patterns and facility levels are replaced
+// only when their settings changed. A Success event (facility "Config")
+// lists the changed settings, or a Fail event says why the file was
+// rejected; a rejected file leaves the logger as it was. Settings
+// Reconfigure cannot change at runtime (QueueSize, PriorityReserve,
+// Synchronous) cause a rejection. SetFacilityLevel overrides are discarded
+// when the file's FacilityLevels change.
+//
+// The watcher stops by itself when the logger shuts down. WatchConfig
+// returns an error if the file cannot be read.
+func (l *Logger) WatchConfig(path string, interval time.Duration) (*ConfigWatcher, error) {
+	if l == nil {
+		return nil, ErrNotRunning
+	}
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+	w := &ConfigWatcher{
+		logger:   l,
+		path:     path,
+		interval: interval,
+		stop:     make(chan struct{}),
+		exited:   make(chan struct{}),
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	w.modTime, w.size, w.sum = info.ModTime(), info.Size(), sha256.Sum256(data)
+	go w.run()
+	return w, nil
+}
+
+// Stop stops the watcher and waits for a reload in progress to finish. It is
+// safe to call more than once.
+func (w *ConfigWatcher) Stop() {
+	if w == nil {
+		return
+	}
+	w.stopOnce.Do(func() { close(w.stop) })
+	<-w.exited
+}
+
+// run polls until Stop or logger shutdown.
+func (w *ConfigWatcher) run() {
+	defer close(w.exited)
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-w.logger.agent.done:
+			return
+		case <-ticker.C:
+			w.poll()
+		}
+	}
+}
+
+// poll reloads the file if it changed since the last poll.
+func (w *ConfigWatcher) poll() {
+	info, err := os.Stat(w.path)
+	if err != nil {
+		// Editors often replace the file by renaming; a missing file is
+		// picked up again on a later poll, so only report other errors once.
+		if !os.IsNotExist(err) && !w.modTime.IsZero() {
+			w.logger.Fail(watchIface, "config reload from %s failed: %v", w.path, err)
+		}
+		w.modTime, w.size = time.Time{}, -1
+		return
+	}
+	unchanged := info.ModTime().Equal(w.modTime) && info.Size() == w.size
+	if unchanged && !w.settling {
+		return
+	}
+	w.modTime, w.size = info.ModTime(), info.Size()
+	data, err := os.ReadFile(w.path)
+	if err != nil {
+		w.logger.Fail(watchIface, "config reload from %s failed: %v", w.path, err)
+		return
+	}
+	sum := sha256.Sum256(data)
+	if !unchanged || sum != w.pending || !w.settling {
+		// Still being written, perhaps: look again on the next poll.
+		w.pending, w.settling = sum, true
+		return
+	}
+	w.settling = false
+	if sum == w.sum {
+		return
+	}
+	w.sum = sum
+	w.reload()
+}
+
+// reload loads the file, merges it with the running Config and applies it.
+func (w *ConfigWatcher) reload() {
+	cfg, err := LoadConfig(w.path)
+	if err != nil {
+		w.logger.Fail(watchIface, "config reload rejected: %v", err)
+		return
+	}
+	cur := *w.logger.agent.cfg.Load()
+	cfg.Hooks = cur.Hooks
+	cfg.ContextExtractors = cur.ContextExtractors
+	cfg.OnSinkError = cur.OnSinkError
+	cfg.DiagnosticsOutput = cur.DiagnosticsOutput
+	cfg.Sinks = withInstances(cfg.Sinks, cur.Sinks)
+	changes := configChanges(cur, cfg)
+	if len(changes) == 0 {
+		return
+	}
+	if err := w.logger.agent.reconfigure(cfg, true); err != nil {
+		w.logger.Fail(watchIface, "config reload from %s rejected: %v", w.path, err)
+		return
+	}
+	w.logger.Success(watchIface, "config reloaded from %s: %s", w.path, strings.Join(changes, "; "))
+}
+
+// withInstances returns the sinks from a file with the running Config's
+// SinkConfig.Instance sinks put back at their positions: each other running
+// sink's place is taken by the next sink from the file, and any further file
+// sinks follow. An unchanged file thus yields the running list.
+func withInstances(file, running []SinkConfig) []SinkConfig {
+	var out []SinkConfig
+	next := 0
+	for _, s := range running {
+		switch {
+		case s.Instance != nil:
+			out = append(out, s)
+		case next < len(file):
+			out = append(out, file[next])
+			next++
+		}
+	}
+	return append(out, file[next:]...)
+}
+
+// configChanges describes the settings that differ between two Configs.
+// Hooks, context extractors, OnSinkError and DiagnosticsOutput are not
+// compared.
+func configChanges(old, cfg Config) []string {
+	var changes []string
+	diff := func(name string, a, b interface{}) {
+		if !reflect.DeepEqual(a, b) {
+			changes = append(changes, name)
+		}
+	}
+	diff("QueueSize", old.QueueSize, cfg.QueueSize)
+	diff("DropPolicy", old.DropPolicy, cfg.DropPolicy)
+	diff("BlockTimeout", old.BlockTimeout, cfg.BlockTimeout)
+	diff("PriorityReserve", old.PriorityReserve, cfg.PriorityReserve)
+	diff("ShedThresholds", old.ShedThresholds, cfg.ShedThresholds)
+	diff("Synchronous", old.Synchronous, cfg.Synchronous)
+	diff("MaxConsecutivePanics", old.MaxConsecutivePanics, cfg.MaxConsecutivePanics)
+	diff("Console", old.Console, cfg.Console)
+	diff("File", old.File, cfg.File)
+	diff("Dedupe", old.Dedupe, cfg.Dedupe)
+	diff("Audio", old.Audio, cfg.Audio)
+	if len(old.Sinks) > 0 || len(cfg.Sinks) > 0 {
+		diff("Sinks", old.Sinks, cfg.Sinks)
+	}
+	diff("Caller", old.Caller, cfg.Caller)
+	if c := levelChanges(old.FacilityLevels, cfg.FacilityLevels); c != "" {
+		changes = append(changes, "FacilityLevels "+c)
+	}
+	diff("Redaction", old.Redaction, cfg.Redaction)
+	return changes
+}
+
+// levelChanges describes per-pattern facility level changes, e.g.
+// "(SIP.*: INFO -> DEBUG, RTP: removed)", or returns "" if there are none.
+func levelChanges(old, cur FacilityLevels) string {
+	var parts []string
+	for pattern, level := range cur {
+		prev, ok := old[pattern]
+		switch {
+		case !ok:
+			parts = append(parts, fmt.Sprintf("%s: %s", pattern, level))
+		case prev != level:
+			parts = append(parts, fmt.Sprintf("%s: %s -> %s", pattern, prev, level))
+		}
+	}
+	for pattern := range old {
+		if _, ok := cur[pattern]; !ok {
+			parts = append(parts, pattern+": removed")
+		}
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	sort.Strings(parts)
+	return "(" + strings.Join(parts, ", ") + ")"
+}
@@ -0,0 +1,263 @@
+package clog
+
+import (
+	"context"
+	"crypto/sha256"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rewriteConfig replaces the file's content atomically, by writing a
+// temporary file and renaming it over path, with its mtime moved forward so
+// the change is seen even on filesystems with coarse timestamps.
+func rewriteConfig(t *testing.T, path, content string, step int) {
+	t.Helper()
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	mtime := time.Now().Add(time.Duration(step) * time.Second)
+	if err := os.Chtimes(tmp, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWatchConfig_AppliesAndRejects(t *testing.T) {
+	path := writeConfigFile(t, "logging.yaml", "console: {enabled: false}\nsynchronous: true\nfacility_levels: {'*': info}\n")
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hook := &testHook{}
+	cfg.Hooks.Global = []Hook{hook}
+	l, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer l.Shutdown(context.Background())
+	w, err := l.WatchConfig(path, 5*time.Millisecond)
+	if err != nil {
+		t.Fatalf("WatchConfig: %v", err)
+	}
+	defer w.Stop()
+
+	if l.Enabled(LevelDebug, "SIP") {
+		t.Fatal("SIP debug enabled before the reload")
+	}
+	rewriteConfig(t, path, "console: {enabled: false}\nsynchronous: true\nfacility_levels: {'*': info, SIP: debug}\n", 1)
+	events := waitForEvents(hook, 1)
+	if len(events) != 1 || events[0].Level != LevelSuccess || events[0].Iface != "Config" ||
+		!strings.Contains(events[0].Message, "FacilityLevels (SIP: DEBUG)") {
+		t.Fatalf("events = %+v, want one Success naming the SIP level", events)
+	}
+	if !l.Enabled(LevelDebug, "SIP") {
+		t.Error("SIP debug not enabled after the reload")
+	}
+	// The hooks were kept from the running Config.
+	l.Debug("SIP", "INVITE")
+	if events := waitForEvents(hook, 2); len(events) != 2 {
+		t.Fatalf("got %d events after the reload, want 2", len(events))
+	}
+
+	rewriteConfig(t, path, "console: {enabled: false}\nsynchronous: true\nfacility_levels: {'*': loud}\n", 2)
+	events = waitForEvents(hook, 3)
+	if len(events) != 3 || events[2].Level != LevelFail || !strings.Contains(events[2].Message, `unknown level "loud"`) {
+		t.Fatalf("events = %+v, want a Fail for the bad level", events)
+	}
+	rewriteConfig(t, path, "console: {enabled: false}\nfacility_levels: {'*': info, SIP: debug}\n", 3)
+	events = waitForEvents(hook, 4)
+	if len(events) != 4 || events[3].Level != LevelFail || !strings.Contains(events[3].Message, "synchronous mode") {
+		t.Fatalf("events = %+v, want a Fail for the Synchronous change", events)
+	}
+	if !l.Enabled(LevelDebug, "SIP") {
+		t.Error("a rejected reload changed the levels")
+	}
+}
+
+func TestWatchConfig_IgnoresUnchangedContent(t *testing.T) {
+	content := "console: {enabled: false}\nsynchronous: true\n"
+	path := writeConfigFile(t, "logging.yaml", content)
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hook := &testHook{}
+	cfg.Hooks.Global = []Hook{hook}
+	l, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	w, err := l.WatchConfig(path, 5*time.Millisecond)
+	if err != nil {
+		t.Fatalf("WatchConfig: %v", err)
+	}
+	rewriteConfig(t, path, content, 1)
+	rewriteConfig(t, path, "# same settings\n"+content, 2)
+	time.Sleep(50 * time.Millisecond)
+	if events := hook.getEvents(); len(events) != 0 {
+		t.Errorf("events = %+v, want none", events)
+	}
+
+	// Shutting the logger down stops the watcher.
+	l.Shutdown(context.Background())
+	done := make(chan struct{})
+	go func() { w.Stop(); close(done) }()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Stop did not return after Shutdown")
+	}
+
+	if _, err := l.WatchConfig(path+".missing", 0); !os.IsNotExist(err) {
+		t.Errorf("missing file: err = %v", err)
+	}
+}
+
+func TestWatchConfig_WaitsForFileToSettle(t *testing.T) {
+	path := writeConfigFile(t, "logging.yaml", "console: {enabled: false}\nsynchronous: true\n")
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hook := &testHook{}
+	cfg.Hooks.Global = []Hook{hook}
+	l, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer l.Shutdown(context.Background())
+	// Polled by hand: the watcher's goroutine is never started.
+	w := &ConfigWatcher{logger: l, path: path}
+	info, _ := os.Stat(path)
+	data, _ := os.ReadFile(path)
+	w.modTime, w.size, w.sum = info.ModTime(), info.Size(), sha256.Sum256(data)
+
+	// A half-written file is seen once and left alone.
+	rewriteConfig(t, path, "console: {enabled: false}\nsynchro", 1)
+	w.poll()
+	rewriteConfig(t, path, "console: {enabled: false}\nsynchronous: true\ncaller: {enabled: true}\n", 2)
+	w.poll()
+	if events := hook.getEvents(); len(events) != 0 {
+		t.Fatalf("events = %+v before the file settled, want none", events)
+	}
+	w.poll()
+	events := hook.getEvents()
+	if len(events) != 1 || events[0].Level != LevelSuccess || !strings.Contains(events[0].Message, "Caller") {
+		t.Fatalf("events = %+v, want one Success for the settled file", events)
+	}
+	w.poll()
+	if events := hook.getEvents(); len(events) != 1 {
+		t.Errorf("events = %+v, want the settled file applied once", events)
+	}
+}
+
+func TestWatchConfig_KeepsInstanceSinkPositions(t *testing.T) {
+	path := writeConfigFile(t, "logging.yaml", `console: {enabled: false}
+synchronous: true
+sinks:
+  - {type: betterstack, token: t, endpoint: "http://127.0.0.1:1", min_level: catastrophe}
+`)
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hook := &testHook{}
+	cfg.Hooks.Global = []Hook{hook}
+	mine := &captureSink{}
+	cfg.Sinks = append([]SinkConfig{{Name: "mine", Instance: mine}}, cfg.Sinks...)
+	l, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer l.Shutdown(context.Background())
+	w := &ConfigWatcher{logger: l, path: path}
+
+	data, _ := os.ReadFile(path)
+	rewriteConfig(t, path, string(data)+"caller: {enabled: true}\n", 1)
+	w.poll()
+	w.poll()
+	events := hook.getEvents()
+	if len(events) != 1 || !strings.HasSuffix(events[0].Message, ": Caller") {
+		t.Fatalf("events = %+v, want a Success naming only Caller", events)
+	}
+	if got := l.agent.cfg.Load().Sinks; len(got) != 2 || got[0].Instance != Sink(mine) {
+		t.Errorf("Sinks after reload = %+v, want the instance first", got)
+	}
+}
+
+func TestWatchConfig_AppliesMaxConsecutivePanics(t *testing.T) {
+	path := writeConfigFile(t, "logging.yaml", "console: {enabled: false}\nsynchronous: true\n")
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hook := &testHook{}
+	cfg.Hooks.Global = []Hook{hook}
+	l, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer l.Shutdown(context.Background())
+	w := &ConfigWatcher{logger: l, path: path}
+
+	rewriteConfig(t, path, "console: {enabled: false}\nsynchronous: true\nmax_consecutive_panics: 7\n", 1)
+	w.poll()
+	w.poll()
+	events := hook.getEvents()
+	if len(events) != 1 || !strings.HasSuffix(events[0].Message, ": MaxConsecutivePanics") {
+		t.Fatalf("events = %+v, want a Success naming MaxConsecutivePanics", events)
+	}
+	if got := l.agent.cfg.Load().MaxConsecutivePanics; got != 7 {
+		t.Errorf("MaxConsecutivePanics = %d after reload, want 7", got)
+	}
+}
+
+func TestWatchConfig_RebuildsOnlyChangedSinks(t *testing.T) {
+	dir := t.TempDir()
+	base := "console: {enabled: false}\nfile: {base_dir: " + dir + "}\n"
+	sink := func(minLevel string) string {
+		return "sinks:\n  - {type: betterstack, token: t, endpoint: \"http://127.0.0.1:1\", min_level: " + minLevel + "}\n"
+	}
+	path := writeConfigFile(t, "logging.yaml", base+sink("catastrophe"))
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer l.Shutdown(context.Background())
+	w := &ConfigWatcher{logger: l, path: path}
+	running := func() []Sink { return *l.agent.activeSinks.Load() }
+	reload := func(content string, step int) {
+		t.Helper()
+		rewriteConfig(t, path, content, step)
+		w.poll()
+		w.poll()
+		if err := l.Flush(context.Background()); err != nil {
+			t.Fatalf("Flush: %v", err)
+		}
+	}
+	before, hooks := running(), l.agent.hooks
+
+	reload(base+"facility_levels: {SIP: warning}\n"+sink("catastrophe"), 1)
+	after := running()
+	if len(after) != 2 || after[0] != before[0] || after[1] != before[1] || l.agent.hooks != hooks {
+		t.Fatal("a FacilityLevels edit restarted sinks or hooks")
+	}
+	if l.Enabled(LevelInfo, "SIP") {
+		t.Error("the new facility level was not applied")
+	}
+
+	reload(base+"facility_levels: {SIP: warning}\n"+sink("error"), 2)
+	after = running()
+	if len(after) != 2 || after[0] != before[0] || after[1] == before[1] {
+		t.Error("want the file sink kept and only the edited sink rebuilt")
+	}
+}
@@ -2,9 +2,8 @@
 package clog
 
 import (
-	"fmt"
+	"io"
 	"os"
-	"time"
 
 	"github.com/LastBotInc/coralie-logging-go/internal/term"
 	"github.com/LastBotInc/coralie-logging-go/internal/timefmt"
@@ -13,8 +12,8 @@ import (
 // consoleSink handles console output with optional colors and emojis.
 type consoleSink struct {
 	cfg      ConsoleConfig
-	useColor bool
-	useEmoji bool
+	useColor bool      // colors imply level emojis
+	out      io.Writer // nil writes to os.Stdout
 }
 
 // newConsoleSink creates a new console sink.
@@ -26,88 +25,113 @@ func newConsoleSink(cfg ConsoleConfig) *consoleSink {
 	return &consoleSink{
 		cfg:      cfg,
 		useColor: useColor,
-		useEmoji: useColor, // Use emojis when colors are enabled
 	}
 }
 
-// write writes a formatted message to console.
-// Format: [<timestamp>][<emoji+level>][<facility>][<message>]
-func (s *consoleSink) write(level Level, iface, formatted string) {
-	// Check if level should be omitted
+// lineFormat implements lineSink. Without colors the console line is the
+// same text line the file sink writes.
+func (s *consoleSink) lineFormat() lineFormat {
+	if s.useColor {
+		return lineConsoleColor
+	}
+	return lineText
+}
+
+// writeLine implements lineSink.
+func (s *consoleSink) writeLine(level Level, _ string, line []byte) error {
 	if s.cfg.OmitLevels != nil && s.cfg.OmitLevels[level] {
-		return
+		return nil
+	}
+	out := s.out
+	if out == nil {
+		out = os.Stdout
 	}
+	_, err := out.Write(line)
+	return err
+}
+
+// write formats and writes one event to console.
+func (s *consoleSink) write(e Event) error {
+	buf := getBuffer()
+	defer buf.free()
+	buf.b = appendLine(buf.b, e, s.lineFormat())
+	return s.writeLine(e.Level, e.Iface, buf.b)
+}
 
+// appendConsoleLine appends the colored console line
+// [<timestamp>][<emoji+level>][<facility>]<message> key=value... caller=...
+// to b. All brackets are dark gray; the message is light gray, green for
+// success, yellow for warning and red for fail, error and catastrophe.
+func appendConsoleLine(b []byte, e Event) []byte {
+	level := e.Level
 	levelStr := level.String()
-	now := time.Now()
-	timestamp := timefmt.Format(now, "")
+	bracket := func(b []byte, c byte) []byte {
+		b = append(b, term.ColorDarkGray...)
+		b = append(b, c)
+		return append(b, term.ColorReset...)
+	}
 
-	var output string
+	b = bracket(b, '[')
+	b = timefmt.Append(b, eventTime(e), "")
+	b = bracket(b, ']')
 
-	if s.useColor {
-		// Format: [<timestamp>][<emoji+level>][<facility>]<message>
-		// All brackets: dark gray
-		bracketColor := term.ColorDarkGray
-		
-		// Timestamp: dark gray brackets
-		timestampPart := bracketColor + "[" + term.ColorReset + timestamp + bracketColor + "]" + term.ColorReset
-
-		// Emoji + Level: emoji + (optional space) + colored level string, dark gray brackets
-		levelColor := term.LevelColor(levelStr)
-		emojiLevelPart := ""
-		if s.useEmoji {
-			emoji := term.LevelEmoji(levelStr)
-			// Add space for INFO, WARNING, DEBUG; no space for SUCCESS, FAIL, ERROR, CATASTROPHE
-			space := " "
-			if level == LevelSuccess || level == LevelFail || level == LevelError || level == LevelCatastrophe || level == LevelDebug {
-				space = ""
-			}
-			emojiLevelPart = emoji + space + levelColor + levelStr + term.ColorReset
-		} else {
-			emojiLevelPart = levelColor + levelStr + term.ColorReset
-		}
-		emojiLevelPart = bracketColor + "[" + term.ColorReset + emojiLevelPart + bracketColor + "]" + term.ColorReset
-
-		// Facility: bright white text, dark gray brackets
-		facilityPart := bracketColor + "[" + term.ColorReset + term.ColorBrightWhite + iface + term.ColorReset + bracketColor + "]" + term.ColorReset
-
-		// Message: light gray (default), green for success, yellow for warning, red for fail/error/catastrophe
-		// No brackets around message
-		messageColor := term.ColorLightGray
-		switch level {
-		case LevelSuccess:
-			messageColor = term.ColorGreen
-		case LevelWarning:
-			messageColor = term.ColorYellow
-		case LevelFail, LevelError, LevelCatastrophe:
-			messageColor = term.ColorRed
-		}
-		messagePart := messageColor + formatted + term.ColorReset
-
-		output = timestampPart + emojiLevelPart + facilityPart + messagePart
-	} else {
-		// No colors: plain format
-		emojiLevelPart := ""
-		if s.useEmoji {
-			emoji := term.LevelEmoji(levelStr)
-			// Add space for INFO, WARNING; no space for SUCCESS, FAIL, ERROR, CATASTROPHE, DEBUG
-			space := " "
-			if level == LevelSuccess || level == LevelFail || level == LevelError || level == LevelCatastrophe || level == LevelDebug {
-				space = ""
-			}
-			emojiLevelPart = emoji + space + levelStr
-		} else {
-			emojiLevelPart = levelStr
-		}
-		output = fmt.Sprintf("[%s][%s][%s]%s", timestamp, emojiLevelPart, iface, formatted)
+	b = bracket(b, '[')
+	b = append(b, term.LevelEmoji(levelStr)...)
+	// Space after the emoji for INFO and WARNING only.
+	if level == LevelInfo || level == LevelWarning {
+		b = append(b, ' ')
 	}
-
-	_, _ = fmt.Fprintln(os.Stdout, output)
+	b = append(b, term.LevelColor(levelStr)...)
+	b = append(b, levelStr...)
+	b = append(b, term.ColorReset...)
+	b = bracket(b, ']')
+
+	b = bracket(b, '[')
+	b = append(b, term.ColorBrightWhite...)
+	b = append(b, e.Iface...)
+	b = append(b, term.ColorReset...)
+	b = bracket(b, ']')
+
+	messageColor := term.ColorLightGray
+	switch level {
+	case LevelSuccess:
+		messageColor = term.ColorGreen
+	case LevelWarning:
+		messageColor = term.ColorYellow
+	case LevelFail, LevelError, LevelCatastrophe:
+		messageColor = term.ColorRed
+	}
+	b = append(b, messageColor...)
+	b = append(b, e.Message...)
+	b = append(b, term.ColorReset...)
+
+	// Fields: dark gray key=value pairs after the message.
+	if len(e.Fields) > 0 || e.Caller != "" {
+		b = append(b, term.ColorDarkGray...)
+		b = appendEventSuffix(b, e)
+		b = append(b, term.ColorReset...)
+	}
+	return append(b, '\n')
 }
 
 // Write implements Sink. Writes a formatted message to console.
 func (s *consoleSink) Write(level Level, iface, formatted string) {
-	s.write(level, iface, formatted)
+	_ = s.write(Event{Level: level, Iface: iface, Message: formatted})
+}
+
+// WriteEvent implements EventSink. Writes the message and its fields to console.
+func (s *consoleSink) WriteEvent(e Event) {
+	_ = s.write(e)
+}
+
+// WriteErr implements ErrorWriter.
+func (s *consoleSink) WriteErr(e Event) error {
+	return s.write(e)
+}
+
+// Enabled implements LevelEnabler.
+func (s *consoleSink) Enabled(level Level, _ string) bool {
+	return !s.cfg.OmitLevels[level]
 }
 
 // Flush implements Sink. No-op for console.
@@ -0,0 +1,219 @@
+// Package clog: context-aware logging and correlation-ID extraction.
+package clog
+
+import (
+	"context"
+	"strings"
+)
+
+// ContextExtractor pulls correlation values out of a context and appends them
+// to fields as structured Fields. Extractors run on the caller's goroutine in
+// the *Ctx logging functions, in the order they are listed in
+// Config.ContextExtractors; they must be cheap and must not log.
+type ContextExtractor func(ctx context.Context, fields []Field) []Field
+
+type contextKey int
+
+const (
+	traceParentKey contextKey = iota
+	requestIDKey
+	callIDKey
+)
+
+// ContextWithTraceParent returns a copy of ctx carrying a W3C traceparent
+// header value ("00-<trace-id>-<parent-id>-<flags>"), e.g. as received on an
+// incoming HTTP request. TraceParentExtractor turns it into trace_id and
+// span_id fields.
+func ContextWithTraceParent(ctx context.Context, traceparent string) context.Context {
+	return context.WithValue(ctx, traceParentKey, traceparent)
+}
+
+// ContextWithRequestID returns a copy of ctx carrying a request ID, logged as
+// request_id by RequestIDExtractor.
+func ContextWithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey, id)
+}
+
+// ContextWithCallID returns a copy of ctx carrying a call ID, logged as
+// call_id by CallIDExtractor.
+func ContextWithCallID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, callIDKey, id)
+}
+
+// TraceParentExtractor adds trace_id and span_id from a traceparent stored
+// with ContextWithTraceParent. Malformed values and the all-zero IDs the W3C
+// spec declares invalid are ignored.
+func TraceParentExtractor(ctx context.Context, fields []Field) []Field {
+	tp, _ := ctx.Value(traceParentKey).(string)
+	traceID, spanID, ok := ParseTraceParent(tp)
+	if !ok {
+		return fields
+	}
+	return append(fields, String("trace_id", traceID), String("span_id", spanID))
+}
+
+// RequestIDExtractor adds request_id from ContextWithRequestID.
+func RequestIDExtractor(ctx context.Context, fields []Field) []Field {
+	if id, _ := ctx.Value(requestIDKey).(string); id != "" {
+		fields = append(fields, String("request_id", id))
+	}
+	return fields
+}
+
+// CallIDExtractor adds call_id from ContextWithCallID.
+func CallIDExtractor(ctx context.Context, fields []Field) []Field {
+	if id, _ := ctx.Value(callIDKey).(string); id != "" {
+		fields = append(fields, String("call_id", id))
+	}
+	return fields
+}
+
+// DefaultContextExtractors returns the built-in extractors (traceparent,
+// request ID, call ID). DefaultConfig registers them.
+func DefaultContextExtractors() []ContextExtractor {
+	return []ContextExtractor{TraceParentExtractor, RequestIDExtractor, CallIDExtractor}
+}
+
+// ParseTraceParent parses a W3C traceparent header value and returns its
+// lowercase hex trace ID (32 chars) and parent span ID (16 chars). It accepts
+// future versions as the spec requires, provided the first four fields are
+// well-formed, and rejects version ff and all-zero IDs.
+func ParseTraceParent(tp string) (traceID, spanID string, ok bool) {
+	tp = strings.TrimSpace(tp)
+	// version(2) - trace-id(32) - parent-id(16) - flags(2)
+	if len(tp) < 55 || tp[2] != '-' || tp[35] != '-' || tp[52] != '-' {
+		return "", "", false
+	}
+	if len(tp) > 55 && tp[55] != '-' {
+		return "", "", false
+	}
+	version, traceID, spanID, flags := tp[0:2], tp[3:35], tp[36:52], tp[53:55]
+	if !isLowerHex(version) || version == "ff" || !isLowerHex(flags) {
+		return "", "", false
+	}
+	if version == "00" && len(tp) != 55 {
+		return "", "", false
+	}
+	if !isLowerHex(traceID) || !isLowerHex(spanID) || isAllZero(traceID) || isAllZero(spanID) {
+		return "", "", false
+	}
+	return traceID, spanID, true
+}
+
+// isLowerHex reports whether s is non-empty and consists of [0-9a-f].
+func isLowerHex(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// isAllZero reports whether s consists only of '0'.
+func isAllZero(s string) bool {
+	return strings.Trim(s, "0") == ""
+}
+
+// logCtx is log with the bound fields extended by the context extractors.
+func (l *Logger) logCtx(ctx context.Context, level Level, iface, msg string, params []interface{}) {
+	if l == nil {
+		return
+	}
+	facility := l.facility(iface)
+	if !l.agent.levelEnabled(facility, level) {
+		return
+	}
+	l.logFields(level, facility, msg, params, l.contextFields(ctx))
+}
+
+// contextFields returns the logger's bound fields followed by whatever the
+// configured extractors pull out of ctx. Without extractors (or a ctx) the
+// bound slice is returned as-is.
+func (l *Logger) contextFields(ctx context.Context) []Field {
+	extractors := l.agent.cfg.Load().ContextExtractors
+	if ctx == nil || len(extractors) == 0 {
+		return l.fields
+	}
+	fields := l.fields[:len(l.fields):len(l.fields)]
+	for _, extract := range extractors {
+		fields = extract(ctx, fields)
+	}
+	return fields
+}
+
+// DebugCtx logs a debug message with correlation fields extracted from ctx.
+func (l *Logger) DebugCtx(ctx context.Context, iface, msg string, params ...interface{}) {
+	l.logCtx(ctx, LevelDebug, iface, msg, params)
+}
+
+// InfoCtx logs an info message with correlation fields extracted from ctx.
+func (l *Logger) InfoCtx(ctx context.Context, iface, msg string, params ...interface{}) {
+	l.logCtx(ctx, LevelInfo, iface, msg, params)
+}
+
+// SuccessCtx logs a success message with correlation fields extracted from ctx.
+func (l *Logger) SuccessCtx(ctx context.Context, iface, msg string, params ...interface{}) {
+	l.logCtx(ctx, LevelSuccess, iface, msg, params)
+}
+
+// WarningCtx logs a warning message with correlation fields extracted from ctx.
+func (l *Logger) WarningCtx(ctx context.Context, iface, msg string, params ...interface{}) {
+	l.logCtx(ctx, LevelWarning, iface, msg, params)
+}
+
+// FailCtx logs a fail message with correlation fields extracted from ctx.
+func (l *Logger) FailCtx(ctx context.Context, iface, msg string, params ...interface{}) {
+	l.logCtx(ctx, LevelFail, iface, msg, params)
+}
+
+// ErrorCtx logs an error message with correlation fields extracted from ctx.
+func (l *Logger) ErrorCtx(ctx context.Context, iface, msg string, params ...interface{}) {
+	l.logCtx(ctx, LevelError, iface, msg, params)
+}
+
+// CatastropheCtx logs a catastrophe message with correlation fields extracted
+// from ctx.
+func (l *Logger) CatastropheCtx(ctx context.Context, iface, msg string, params ...interface{}) {
+	l.logCtx(ctx, LevelCatastrophe, iface, msg, params)
+}
+
+// DebugCtx logs a debug message with correlation fields extracted from ctx.
+func DebugCtx(ctx context.Context, iface, msg string, params ...interface{}) {
+	Default().logCtx(ctx, LevelDebug, iface, msg, params)
+}
+
+// InfoCtx logs an info message with correlation fields extracted from ctx.
+func InfoCtx(ctx context.Context, iface, msg string, params ...interface{}) {
+	Default().logCtx(ctx, LevelInfo, iface, msg, params)
+}
+
+// SuccessCtx logs a success message with correlation fields extracted from ctx.
+func SuccessCtx(ctx context.Context, iface, msg string, params ...interface{}) {
+	Default().logCtx(ctx, LevelSuccess, iface, msg, params)
+}
+
+// WarningCtx logs a warning message with correlation fields extracted from ctx.
+func WarningCtx(ctx context.Context, iface, msg string, params ...interface{}) {
+	Default().logCtx(ctx, LevelWarning, iface, msg, params)
+}
+
+// FailCtx logs a fail message with correlation fields extracted from ctx.
+func FailCtx(ctx context.Context, iface, msg string, params ...interface{}) {
+	Default().logCtx(ctx, LevelFail, iface, msg, params)
+}
+
+// ErrorCtx logs an error message with correlation fields extracted from ctx.
+func ErrorCtx(ctx context.Context, iface, msg string, params ...interface{}) {
+	Default().logCtx(ctx, LevelError, iface, msg, params)
+}
+
+// CatastropheCtx logs a catastrophe message with correlation fields extracted
+// from ctx.
+func CatastropheCtx(ctx context.Context, iface, msg string, params ...interface{}) {
+	Default().logCtx(ctx, LevelCatastrophe, iface, msg, params)
+}
@@ -0,0 +1,126 @@
+package clog
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+)
+
+const testTraceParent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+func TestParseTraceParent(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		ok   bool
+	}{
+		{"valid", testTraceParent, true},
+		{"future_version_with_suffix", "cc-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", true},
+		{"v00_with_suffix", testTraceParent + "-extra", false},
+		{"version_ff", "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", false},
+		{"zero_trace", "00-00000000000000000000000000000000-00f067aa0ba902b7-01", false},
+		{"zero_span", "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", false},
+		{"uppercase", "00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01", false},
+		{"short", "00-4bf92f35-00f067aa-01", false},
+		{"empty", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			traceID, spanID, ok := ParseTraceParent(tt.in)
+			if ok != tt.ok {
+				t.Fatalf("ParseTraceParent(%q) ok = %v, want %v", tt.in, ok, tt.ok)
+			}
+			if ok && (traceID != "4bf92f3577b34da6a3ce929d0e0e4736" || spanID != "00f067aa0ba902b7") {
+				t.Errorf("got trace=%q span=%q", traceID, spanID)
+			}
+		})
+	}
+}
+
+func TestInfoCtx_ExtractsCorrelationFields(t *testing.T) {
+	hook := &testHook{}
+	cfg := DefaultConfig()
+	cfg.Console.Enabled = false
+	cfg.Dedupe.Enabled = false
+	cfg.Hooks.Global = []Hook{hook}
+	Init(cfg)
+
+	ctx := ContextWithTraceParent(context.Background(), testTraceParent)
+	ctx = ContextWithRequestID(ctx, "req-9")
+	ctx = ContextWithCallID(ctx, "call-7")
+
+	InfoCtx(ctx, "API", "handled", Int("status", 200))
+	ErrorCtx(context.Background(), "API", "no correlation")
+	Info("API", "plain")
+
+	Shutdown(context.Background())
+
+	events := hook.getEvents()
+	if len(events) != 3 {
+		t.Fatalf("got %d events, want 3", len(events))
+	}
+	got := fieldMap(events[0])
+	want := map[string]string{
+		"trace_id":   "4bf92f3577b34da6a3ce929d0e0e4736",
+		"span_id":    "00f067aa0ba902b7",
+		"request_id": "req-9",
+		"call_id":    "call-7",
+		"status":     "200",
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("field %s = %q, want %q", k, got[k], v)
+		}
+	}
+	if len(events[1].Fields) != 0 || len(events[2].Fields) != 0 {
+		t.Errorf("events without correlation values carry fields: %v / %v", events[1].Fields, events[2].Fields)
+	}
+}
+
+func TestLoggerCtx_CustomExtractorAndBoundFields(t *testing.T) {
+	type tenantKey struct{}
+	hook := &testHook{}
+	cfg := DefaultConfig()
+	cfg.Console.Enabled = false
+	cfg.Hooks.Global = []Hook{hook}
+	cfg.ContextExtractors = []ContextExtractor{
+		func(ctx context.Context, fields []Field) []Field {
+			if v, ok := ctx.Value(tenantKey{}).(string); ok {
+				fields = append(fields, String("tenant", v))
+			}
+			return fields
+		},
+	}
+	l, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	ctx := context.WithValue(context.Background(), tenantKey{}, "acme")
+	ctx = ContextWithCallID(ctx, "ignored") // CallIDExtractor not registered
+	l.With(String("svc", "sip")).WarningCtx(ctx, "SIP", "slow")
+	slog.New(NewSlogHandler(l, nil)).InfoContext(ctx, "via slog")
+
+	l.Shutdown(context.Background())
+
+	events := hook.getEvents()
+	if len(events) != 2 {
+		t.Fatalf("got %d events, want 2", len(events))
+	}
+	got := fieldMap(events[0])
+	if got["svc"] != "sip" || got["tenant"] != "acme" || len(got) != 2 {
+		t.Errorf("fields = %v, want svc and tenant only", got)
+	}
+	if events[0].Fields[0].Key != "svc" {
+		t.Errorf("bound fields should come first: %v", events[0].Fields)
+	}
+	if fieldMap(events[1])["tenant"] != "acme" {
+		t.Errorf("slog handler did not run extractors: %v", events[1].Fields)
+	}
+}
+
+func TestCtx_NilLoggerIsNoop(t *testing.T) {
+	var l *Logger
+	l.InfoCtx(context.Background(), "Test", "discarded")
+	CatastropheCtx(context.Background(), "Test", "discarded before Init")
+}
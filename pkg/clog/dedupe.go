@@ -1,15 +1,21 @@
 // Package clog: deduplication logic for consecutive identical messages.
 package clog
 
-import "fmt"
+import (
+	"fmt"
+	"time"
+)
 
 // dedupeState tracks deduplication state.
 type dedupeState struct {
-	lastLevel    Level
-	lastIface    string
-	lastMessage  string
-	repeatCount  int
-	enabled      bool
+	lastLevel     Level
+	lastIface     string
+	lastMessage   []byte // copy of the last dedupe key
+	lastTime      time.Time
+	lastSeq       uint64
+	repeatCount   int
+	pending       *Event // summary captured when the repeated message changed
+	enabled       bool
 	summaryFormat string
 }
 
@@ -22,41 +28,70 @@ func newDedupeState(cfg DedupeConfig) *dedupeState {
 }
 
 // check checks if an event is a duplicate and returns whether to suppress it.
-// Returns (shouldSuppress, shouldEmitSummary).
-func (d *dedupeState) check(level Level, iface, formatted string) (bool, bool) {
+// key is the raw dedupe key, only read during the call; e supplies level,
+// iface, time and sequence. Returns (shouldSuppress, shouldEmitSummary).
+func (d *dedupeState) check(e Event, key []byte) (bool, bool) {
 	if !d.enabled {
 		return false, false
 	}
 
 	// Check if this matches the last message
-	if d.lastLevel == level && d.lastIface == iface && d.lastMessage == formatted {
+	if d.lastLevel == e.Level && d.lastIface == e.Iface && string(d.lastMessage) == string(key) {
 		d.repeatCount++
+		// The summary is stamped with the last suppressed repeat.
+		d.lastTime = e.Time
+		d.lastSeq = e.Seq
 		return true, false // Suppress this message
 	}
 
-	// Different message - need to emit summary if there were repeats
+	// Different message - need to emit summary if there were repeats. Capture
+	// it now, before the state moves on, so it keeps the repeated message's
+	// level and iface rather than the new one's.
 	shouldEmitSummary := d.repeatCount > 0
+	if shouldEmitSummary {
+		summary := d.summaryEvent()
+		d.pending = &summary
+		d.repeatCount = 0
+	}
 
-	// Update state (but don't reset repeatCount yet - flushSummary will do that)
-	d.lastLevel = level
-	d.lastIface = iface
-	d.lastMessage = formatted
-	// Note: repeatCount will be reset by flushSummary() when summary is emitted
+	d.lastLevel = e.Level
+	d.lastIface = e.Iface
+	d.lastMessage = append(d.lastMessage[:0], key...)
+	d.lastTime = e.Time
+	d.lastSeq = e.Seq
 
 	return false, shouldEmitSummary
 }
 
-// flushSummary returns a summary message if there are pending repeats.
-func (d *dedupeState) flushSummary() (Level, string, string, bool) {
-	if !d.enabled || d.repeatCount == 0 {
-		return 0, "", "", false
+// flushSummary returns a summary event if there are pending repeats: either
+// the one captured when the repeated message changed, or (at shutdown) the
+// repeats of the current message. The event carries the level and iface of the
+// repeated message and the time and sequence number of its last suppressed
+// repeat.
+func (d *dedupeState) flushSummary() (Event, bool) {
+	if !d.enabled {
+		return Event{}, false
 	}
-
-	summary := fmt.Sprintf(d.summaryFormat, d.repeatCount)
+	if d.pending != nil {
+		summary := *d.pending
+		d.pending = nil
+		return summary, true
+	}
+	if d.repeatCount == 0 {
+		return Event{}, false
+	}
+	summary := d.summaryEvent()
 	d.repeatCount = 0 // Reset after flushing
-
-	return d.lastLevel, d.lastIface, summary, true
+	return summary, true
 }
 
-
-
+// summaryEvent builds the summary for the current run of repeats.
+func (d *dedupeState) summaryEvent() Event {
+	return Event{
+		Level:   d.lastLevel,
+		Iface:   d.lastIface,
+		Message: fmt.Sprintf(d.summaryFormat, d.repeatCount),
+		Time:    d.lastTime,
+		Seq:     d.lastSeq,
+	}
+}
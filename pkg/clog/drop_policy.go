@@ -0,0 +1,143 @@
+// Package clog: queue admission and drop policies.
+package clog
+
+import (
+	"fmt"
+	"time"
+)
+
+// Drop policies accepted in Config.DropPolicy.
+const (
+	dropPolicyNew          = "drop_new"
+	dropPolicyOld          = "drop_old"
+	dropPolicyBlock        = "block"
+	dropPolicyBlockForever = "block_forever"
+)
+
+// defaultBlockTimeout is the "block" wait when Config.BlockTimeout is zero.
+const defaultBlockTimeout = 10 * time.Millisecond
+
+// enqueueResult is the outcome of offering an event to the queue.
+type enqueueResult int
+
+const (
+	enqueued enqueueResult = iota
+	// dropped: the queue was full (or the agent shut down) and the policy
+	// did not wait.
+	dropped
+	// droppedTimeout: the "block" policy waited BlockTimeout without space.
+	droppedTimeout
+	// shed: the main queue was past the level's ShedThresholds fill ratio.
+	shed
+)
+
+// validateDropPolicy rejects unknown DropPolicy strings and negative
+// BlockTimeouts instead of silently falling back to drop_new.
+func validateDropPolicy(cfg Config) error {
+	return checkDropPolicy(cfg.DropPolicy, cfg.BlockTimeout)
+}
+
+// checkDropPolicy validates a drop policy name and its block timeout; it is
+// shared by the agent queue and the per-sink buffers.
+func checkDropPolicy(policy string, blockTimeout time.Duration) error {
+	switch policy {
+	case "", dropPolicyNew, dropPolicyOld, dropPolicyBlock, dropPolicyBlockForever:
+	default:
+		return fmt.Errorf("unknown drop policy %q (want drop_new, drop_old, block or block_forever)", policy)
+	}
+	if blockTimeout < 0 {
+		return fmt.Errorf("negative block timeout %v", blockTimeout)
+	}
+	return nil
+}
+
+// enqueue attempts to enqueue an event, applying the drop policy if the queue
+// is full. It reports whether the event was accepted.
+func (a *agent) enqueue(e Event) bool {
+	return a.tryEnqueue(e) == enqueued
+}
+
+// tryEnqueue offers an event to the queue and applies the drop policy when it
+// is full. Levels on the shedding ladder are refused early once the queue
+// crosses their threshold. With a PriorityReserve, events below Error may
+// only fill QueueSize places, so the rest of the queue stays free for Error
+// and Catastrophe. Blocking policies give up as soon as the agent shuts down,
+// and do not wait at all on the logger's own goroutines (a hook or
+// OnSinkError logging through the same logger): only the agent frees room,
+// so there the event is dropped instead.
+func (a *agent) tryEnqueue(e Event) enqueueResult {
+	if a.closed.Load() {
+		return dropped
+	}
+	if a.shouldShed(e.Level) {
+		return shed
+	}
+
+	switch a.queue.offer(e) {
+	case offerOK:
+		return enqueued
+	case offerClosed:
+		return dropped
+	}
+
+	switch a.cfg.Load().DropPolicy {
+	case dropPolicyOld:
+		return a.evictAndOffer(e)
+	case dropPolicyBlock, dropPolicyBlockForever:
+		if a.own.current() {
+			return dropped
+		}
+		return a.wait(func(timeout <-chan time.Time) enqueueResult {
+			switch a.queue.await(e, timeout, nil) {
+			case offerOK:
+				return enqueued
+			case offerFull:
+				return droppedTimeout
+			default:
+				return dropped
+			}
+		})
+	default:
+		return dropped
+	}
+}
+
+// evictAndOffer implements drop_old: it discards the oldest queued event e
+// may displace, counting it as a drop, and offers e again until e is queued.
+// e itself is dropped only when nothing may be displaced: below-Error events
+// never evict Error or Catastrophe ones from the reserve, and control
+// requests are never evicted.
+func (a *agent) evictAndOffer(e Event) enqueueResult {
+	for {
+		old, evicted := a.queue.evict(e)
+		if evicted {
+			a.stats.recordDrop(old.Level)
+		}
+		switch a.queue.offer(e) {
+		case offerOK:
+			return enqueued
+		case offerClosed:
+			return dropped
+		}
+		if !evicted {
+			return dropped
+		}
+		// Another producer took the freed place; evict again.
+	}
+}
+
+// wait runs a blocking offer with the policy's timeout: BlockTimeout for
+// "block", none (a nil channel) for "block_forever".
+func (a *agent) wait(offer func(timeout <-chan time.Time) enqueueResult) enqueueResult {
+	cfg := a.cfg.Load()
+	if cfg.DropPolicy == dropPolicyBlockForever {
+		return offer(nil)
+	}
+	timeout := cfg.BlockTimeout
+	if timeout == 0 {
+		timeout = defaultBlockTimeout
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	return offer(timer.C)
+}
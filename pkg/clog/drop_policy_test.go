@@ -0,0 +1,242 @@
+package clog
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+// gateHook holds the agent inside OnLog until release is closed, signalling
+// started the first time it is entered.
+type gateHook struct {
+	testHook
+	started chan struct{}
+	release chan struct{}
+}
+
+func newGateHook() *gateHook {
+	return &gateHook{started: make(chan struct{}), release: make(chan struct{})}
+}
+
+func (h *gateHook) OnLog(e Event) {
+	select {
+	case <-h.started:
+	default:
+		close(h.started)
+	}
+	<-h.release
+	h.testHook.OnLog(e)
+}
+
+// newGatedLogger returns a Logger with a one-slot queue whose agent is parked
+// in the gate hook processing a first event, so the queue fills after one more.
+// Shedding and the priority reserve are off so only the drop policy applies.
+func newGatedLogger(t *testing.T, policy string, timeout time.Duration) (*Logger, *gateHook) {
+	t.Helper()
+	hook := newGateHook()
+	cfg := DefaultConfig()
+	cfg.Console.Enabled = false
+	cfg.Dedupe.Enabled = false
+	cfg.QueueSize = 1
+	cfg.DropPolicy = policy
+	cfg.BlockTimeout = timeout
+	cfg.PriorityReserve = 0
+	cfg.ShedThresholds = nil
+	cfg.Hooks.Global = []Hook{hook}
+	l, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	l.Info("Test", "in flight")
+	<-hook.started
+	l.Info("Test", "queued")
+	return l, hook
+}
+
+func TestDropPolicy_BlockTimesOut(t *testing.T) {
+	l, hook := newGatedLogger(t, "block", 20*time.Millisecond)
+
+	start := time.Now()
+	l.Warning("Test", "times out")
+	if waited := time.Since(start); waited < 20*time.Millisecond {
+		t.Errorf("block returned after %v, want >= 20ms", waited)
+	}
+	close(hook.release)
+	l.Shutdown(context.Background())
+
+	stats := l.Stats()
+	if stats.TimeoutDropsPerLevel[LevelWarning] != 1 {
+		t.Errorf("TimeoutDropsPerLevel[Warning] = %d, want 1", stats.TimeoutDropsPerLevel[LevelWarning])
+	}
+	if stats.DropsPerLevel[LevelWarning] != 0 {
+		t.Errorf("timeout drop also counted as immediate drop")
+	}
+	if stats.AcceptedCount != 2 || len(hook.getEvents()) != 2 {
+		t.Errorf("accepted = %d, delivered = %d, want 2/2", stats.AcceptedCount, len(hook.getEvents()))
+	}
+}
+
+func TestDropPolicy_BlockWaitsForSpace(t *testing.T) {
+	l, hook := newGatedLogger(t, "block", time.Second)
+
+	time.AfterFunc(10*time.Millisecond, func() { close(hook.release) })
+	l.Info("Test", "waits")
+	l.Shutdown(context.Background())
+
+	stats := l.Stats()
+	if stats.AcceptedCount != 3 || stats.TimeoutDropsPerLevel[LevelInfo] != 0 {
+		t.Errorf("accepted = %d, timeout drops = %d, want 3/0", stats.AcceptedCount, stats.TimeoutDropsPerLevel[LevelInfo])
+	}
+	if got := len(hook.getEvents()); got != 3 {
+		t.Errorf("delivered %d events, want 3", got)
+	}
+}
+
+func TestDropPolicy_BlockForever(t *testing.T) {
+	l, hook := newGatedLogger(t, "block_forever", 0)
+
+	returned := make(chan struct{})
+	go func() {
+		l.Error("Test", "audit")
+		close(returned)
+	}()
+	select {
+	case <-returned:
+		t.Fatal("block_forever returned while the queue was full")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(hook.release)
+	<-returned
+	l.Shutdown(context.Background())
+
+	if got := len(hook.getEvents()); got != 3 {
+		t.Errorf("delivered %d events, want 3", got)
+	}
+}
+
+func TestDropPolicy_DropNewIsImmediate(t *testing.T) {
+	l, hook := newGatedLogger(t, "", time.Second)
+
+	start := time.Now()
+	l.Info("Test", "dropped")
+	if waited := time.Since(start); waited > 500*time.Millisecond {
+		t.Errorf("drop_new waited %v", waited)
+	}
+	close(hook.release)
+	l.Shutdown(context.Background())
+
+	if stats := l.Stats(); stats.DropsPerLevel[LevelInfo] != 1 {
+		t.Errorf("DropsPerLevel[Info] = %d, want 1", stats.DropsPerLevel[LevelInfo])
+	}
+}
+
+func TestDropPolicy_DropOldEvictsOldest(t *testing.T) {
+	l, hook := newGatedLogger(t, "drop_old", 0)
+
+	l.Warning("Test", "newest") // evicts "queued"
+	close(hook.release)
+	l.Shutdown(context.Background())
+
+	var got []string
+	for _, e := range hook.getEvents() {
+		got = append(got, e.Message)
+	}
+	if len(got) != 2 || got[0] != "in flight" || got[1] != "newest" {
+		t.Errorf("delivered %v, want [in flight newest]", got)
+	}
+	stats := l.Stats()
+	if stats.DropsPerLevel[LevelInfo] != 1 || stats.DropsPerLevel[LevelWarning] != 0 {
+		t.Errorf("DropsPerLevel = %v, want the evicted Info only", stats.DropsPerLevel)
+	}
+	if stats.AcceptedCount != 3 {
+		t.Errorf("AcceptedCount = %d, want 3", stats.AcceptedCount)
+	}
+}
+
+// loggingHook logs n lines through l on every event with message "trigger".
+type loggingHook struct {
+	l *Logger
+	n int
+}
+
+func (h *loggingHook) OnLog(e Event) {
+	if e.Message != "trigger" {
+		return
+	}
+	for i := 0; i < h.n; i++ {
+		h.l.Info("Hook", "line %d", i)
+	}
+}
+
+func TestDropPolicy_HookLoggingIntoFullQueueDoesNotBlock(t *testing.T) {
+	for _, policy := range []string{"block", "block_forever"} {
+		t.Run(policy, func(t *testing.T) {
+			hook := &loggingHook{n: 5}
+			cfg := DefaultConfig()
+			cfg.Console.Enabled = false
+			cfg.Dedupe.Enabled = false
+			cfg.QueueSize = 2
+			cfg.PriorityReserve = 0
+			cfg.DropPolicy = policy
+			cfg.BlockTimeout = time.Second
+			cfg.Hooks.Global = []Hook{hook}
+			l, err := New(cfg)
+			if err != nil {
+				t.Fatalf("New: %v", err)
+			}
+			hook.l = l
+
+			start := time.Now()
+			l.Info("Test", "trigger")
+			ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+			defer cancel()
+			if err := l.Flush(ctx); err != nil {
+				t.Fatalf("Flush: %v", err)
+			}
+			if elapsed := time.Since(start); elapsed > cfg.BlockTimeout/2 {
+				t.Errorf("the hook took %v, want no wait for the queue", elapsed)
+			}
+			l.Shutdown(context.Background())
+			if stats := l.Stats(); stats.DropsPerLevel[LevelInfo] != 3 {
+				t.Errorf("DropsPerLevel = %v, want the 3 lines past the queue dropped", stats.DropsPerLevel)
+			}
+		})
+	}
+}
+
+func TestDropPolicy_Validation(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  string
+		timeout time.Duration
+		wantErr string
+	}{
+		{"empty", "", 0, ""},
+		{"drop_new", "drop_new", 0, ""},
+		{"drop_old", "drop_old", 0, ""},
+		{"block", "block", time.Millisecond, ""},
+		{"block_forever", "block_forever", 0, ""},
+		{"unknown", "drop_newest", 0, `unknown drop policy "drop_newest"`},
+		{"negative_timeout", "block", -time.Second, "negative block timeout"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.Console.Enabled = false
+			cfg.DropPolicy = tt.policy
+			cfg.BlockTimeout = tt.timeout
+			l, err := New(cfg)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("New: %v", err)
+				}
+				l.Shutdown(context.Background())
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("New error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -1,15 +1,37 @@
 // Package clog: event model for log entries.
 package clog
 
+import "time"
+
 // Event represents a log event.
+//
+// Fields holds structured key/value pairs. On the way in they come from Field
+// values passed among a call's params; by the time an Event reaches a hook or
+// an EventSink, Message is the formatted, redacted line, Params is nil and
+// every string-like field has been redacted.
+//
+// Time and Seq are stamped at the call site, before the event is enqueued, so
+// every sink reports the same timestamp regardless of queue delay. Seq is a
+// per-logger sequence number (shared by child loggers) that increases by one
+// per log call, including calls whose events are later dropped, so gaps
+// reveal drops and it orders events whose Times are equal.
+//
+// Caller is the "dir/file.go:line:pkg.Func" of the log call when caller
+// capture is enabled (Config.Caller) for the event's level, else empty.
 type Event struct {
 	Level   Level
 	Iface   string
 	Message string
 	Params  []interface{}
-}
-
-
-
-
+	Fields  []Field
+	Time    time.Time
+	Seq     uint64
+	Caller  string
 
+	// pc is the call-site program counter captured by the API; the agent
+	// resolves it into Caller.
+	pc uintptr
+	// ctl marks a control request travelling through the queue instead of a
+	// log event (see Reconfigure).
+	ctl *control
+}
@@ -0,0 +1,122 @@
+package clog
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// slowHook blocks on every event to simulate a backed-up agent.
+type slowHook struct {
+	testHook
+	delay time.Duration
+}
+
+func (h *slowHook) OnLog(e Event) {
+	time.Sleep(h.delay)
+	h.testHook.OnLog(e)
+}
+
+func TestEvent_TimeStampedAtCallSite(t *testing.T) {
+	hook := &slowHook{delay: 50 * time.Millisecond}
+	cfg := DefaultConfig()
+	cfg.Console.Enabled = false
+	cfg.Dedupe.Enabled = false
+	cfg.Hooks.Global = []Hook{hook}
+	l, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	before := time.Now()
+	for i := 0; i < 4; i++ {
+		l.Info("Test", "event %d", i)
+	}
+	after := time.Now()
+	l.Shutdown(context.Background())
+
+	events := hook.getEvents()
+	if len(events) != 4 {
+		t.Fatalf("got %d events, want 4", len(events))
+	}
+	for i, e := range events {
+		if e.Time.Before(before) || e.Time.After(after) {
+			t.Errorf("event %d Time = %v, want within the log calls [%v, %v]", i, e.Time, before, after)
+		}
+		if e.Seq != uint64(i+1) {
+			t.Errorf("event %d Seq = %d, want %d", i, e.Seq, i+1)
+		}
+	}
+}
+
+func TestEvent_SeqSharedByChildrenAndConcurrent(t *testing.T) {
+	hook := &testHook{}
+	cfg := DefaultConfig()
+	cfg.Console.Enabled = false
+	cfg.Dedupe.Enabled = false
+	cfg.Hooks.Global = []Hook{hook}
+	l, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		child := l.Named("W").With(Int("g", g))
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 25; i++ {
+				child.Info("", "tick %d", i)
+			}
+		}()
+	}
+	wg.Wait()
+	l.Shutdown(context.Background())
+
+	seen := make(map[uint64]bool)
+	for _, e := range hook.getEvents() {
+		if seen[e.Seq] {
+			t.Fatalf("duplicate Seq %d", e.Seq)
+		}
+		seen[e.Seq] = true
+	}
+	for s := uint64(1); s <= 100; s++ {
+		if !seen[s] {
+			t.Errorf("missing Seq %d", s)
+		}
+	}
+}
+
+func TestDedupeSummary_UsesLastRepeatTime(t *testing.T) {
+	d := newDedupeState(DedupeConfig{Enabled: true, SummaryFormat: "repeated %d"})
+	t0 := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	at := func(sec int, seq uint64, level Level, iface string) Event {
+		return Event{Level: level, Iface: iface, Time: t0.Add(time.Duration(sec) * time.Second), Seq: seq}
+	}
+
+	d.check(at(0, 1, LevelInfo, "A"), []byte("same"))
+	d.check(at(1, 2, LevelInfo, "A"), []byte("same"))
+	d.check(at(2, 3, LevelInfo, "A"), []byte("same"))
+	_, emit := d.check(at(9, 4, LevelError, "B"), []byte("different"))
+	if !emit {
+		t.Fatal("expected a summary to be due")
+	}
+	summary, ok := d.flushSummary()
+	if !ok {
+		t.Fatal("flushSummary returned nothing")
+	}
+	if summary.Message != "repeated 2" {
+		t.Errorf("Message = %q", summary.Message)
+	}
+	if !summary.Time.Equal(t0.Add(2*time.Second)) || summary.Seq != 3 {
+		t.Errorf("summary stamped %v/%d, want the last repeat (t0+2s/3)", summary.Time, summary.Seq)
+	}
+	if summary.Level != LevelInfo || summary.Iface != "A" {
+		t.Errorf("summary routed to %s/%s, want the repeated message's INFO/A", summary.Level, summary.Iface)
+	}
+	if _, ok := d.flushSummary(); ok {
+		t.Error("summary flushed twice")
+	}
+}
@@ -0,0 +1,179 @@
+// Package clog: per-facility minimum levels with hierarchical names.
+package clog
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// maxLevelCacheEntries bounds the per-facility cache of a levelTable, so
+// facility names built at run time (per call, per session) cannot grow it
+// without limit. Facilities beyond it are resolved on every call.
+const maxLevelCacheEntries = 4096
+
+// FacilityLevels maps facility patterns to the minimum level logged for them.
+// A pattern is an exact facility ("SIP.Transport"), a subtree ("SIP.*" matches
+// "SIP" and every facility below it, such as "SIP.Transport.TLS"), or "*" for
+// every facility. The most specific pattern wins: an exact match, then the
+// subtree with the longest prefix, then "*". Facilities matching no pattern
+// log at every level.
+type FacilityLevels map[string]Level
+
+// ParseFacilityLevels parses a compact spec such as
+// "SIP.*=debug,RTP=warning,*=info". Entries are separated by commas (or
+// semicolons); level names are case-insensitive (debug, info, success,
+// warning/warn, fail, error, catastrophe).
+func ParseFacilityLevels(spec string) (FacilityLevels, error) {
+	out := FacilityLevels{}
+	for _, entry := range strings.FieldsFunc(spec, func(r rune) bool { return r == ',' || r == ';' }) {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		pattern, name, ok := strings.Cut(entry, "=")
+		pattern, name = strings.TrimSpace(pattern), strings.TrimSpace(name)
+		if !ok || pattern == "" {
+			return nil, fmt.Errorf("facility level %q: want pattern=level", entry)
+		}
+		if err := checkFacilityPattern(pattern); err != nil {
+			return nil, err
+		}
+		level, err := ParseLevel(name)
+		if err != nil {
+			return nil, fmt.Errorf("facility level %q: %w", entry, err)
+		}
+		out[pattern] = level
+	}
+	return out, nil
+}
+
+// checkFacilityPattern rejects wildcards anywhere but a trailing ".*" or a
+// lone "*".
+func checkFacilityPattern(pattern string) error {
+	if pattern == "*" {
+		return nil
+	}
+	if strings.Contains(strings.TrimSuffix(pattern, ".*"), "*") || pattern == ".*" {
+		return fmt.Errorf("facility pattern %q: only a trailing \".*\" or \"*\" is supported", pattern)
+	}
+	return nil
+}
+
+// validateFacilityLevels checks every pattern in a configured table.
+func validateFacilityLevels(levels FacilityLevels) error {
+	for p := range levels {
+		if p == "" {
+			return fmt.Errorf("empty facility pattern")
+		}
+		if err := checkFacilityPattern(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// levelTable is an immutable, compiled FacilityLevels with a bounded
+// per-facility cache of resolved minimums. It is replaced as a whole on
+// every change.
+type levelTable struct {
+	patterns FacilityLevels
+	cache    sync.Map // facility -> Level
+	cached   atomic.Int64
+}
+
+// newLevelTable compiles patterns; it returns nil for an empty table so the
+// common case costs one nil check per log call.
+func newLevelTable(patterns FacilityLevels) *levelTable {
+	if len(patterns) == 0 {
+		return nil
+	}
+	t := &levelTable{patterns: make(FacilityLevels, len(patterns))}
+	for p, l := range patterns {
+		t.patterns[p] = l
+	}
+	return t
+}
+
+// min returns the minimum level for facility.
+func (t *levelTable) min(facility string) Level {
+	if v, ok := t.cache.Load(facility); ok {
+		return v.(Level)
+	}
+	level := t.resolve(facility)
+	if t.cached.Load() < maxLevelCacheEntries {
+		if _, loaded := t.cache.LoadOrStore(facility, level); !loaded {
+			t.cached.Add(1)
+		}
+	}
+	return level
+}
+
+// resolve walks from the exact facility up through its ancestors' subtree
+// patterns to "*".
+func (t *levelTable) resolve(facility string) Level {
+	if l, ok := t.patterns[facility]; ok {
+		return l
+	}
+	for prefix := facility; prefix != ""; {
+		if l, ok := t.patterns[prefix+".*"]; ok {
+			return l
+		}
+		i := strings.LastIndexByte(prefix, '.')
+		if i < 0 {
+			break
+		}
+		prefix = prefix[:i]
+	}
+	if l, ok := t.patterns["*"]; ok {
+		return l
+	}
+	return LevelDebug
+}
+
+// levelEnabled reports whether an event at level for facility passes the
+// facility level table. It runs on the caller's goroutine before enqueue.
+func (a *agent) levelEnabled(facility string, level Level) bool {
+	t := a.levels.Load()
+	return t == nil || level >= t.min(facility)
+}
+
+// setFacilityLevel installs a copy of the current table with pattern set.
+func (a *agent) setFacilityLevel(pattern string, level Level) error {
+	if pattern == "" {
+		return fmt.Errorf("empty facility pattern")
+	}
+	if err := checkFacilityPattern(pattern); err != nil {
+		return err
+	}
+	a.levelsMu.Lock()
+	defer a.levelsMu.Unlock()
+	next := FacilityLevels{pattern: level}
+	if cur := a.levels.Load(); cur != nil {
+		for p, l := range cur.patterns {
+			if p != pattern {
+				next[p] = l
+			}
+		}
+	}
+	a.levels.Store(newLevelTable(next))
+	return nil
+}
+
+// SetFacilityLevel sets the minimum level for a facility pattern (see
+// FacilityLevels) on the default logger at runtime.
+func SetFacilityLevel(pattern string, level Level) error {
+	return Default().SetFacilityLevel(pattern, level)
+}
+
+// SetFacilityLevel sets the minimum level for a facility pattern (see
+// FacilityLevels) at runtime. It takes effect for the next log call; events
+// already queued are not re-filtered. Reconfigure replaces the whole table
+// with the new Config's FacilityLevels.
+func (l *Logger) SetFacilityLevel(pattern string, level Level) error {
+	if l == nil {
+		return ErrNotRunning
+	}
+	return l.agent.setFacilityLevel(pattern, level)
+}
@@ -0,0 +1,131 @@
+package clog
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"testing"
+)
+
+func TestParseFacilityLevels(t *testing.T) {
+	got, err := ParseFacilityLevels(" SIP.*=debug, RTP=Warning;*=info ,")
+	if err != nil {
+		t.Fatalf("ParseFacilityLevels: %v", err)
+	}
+	want := FacilityLevels{"SIP.*": LevelDebug, "RTP": LevelWarning, "*": LevelInfo}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s = %s, want %s", k, got[k], v)
+		}
+	}
+
+	for _, bad := range []string{"SIP", "=debug", "SIP=loud", "S*P=info", "*.SIP=info", ".*=info"} {
+		if _, err := ParseFacilityLevels(bad); err == nil {
+			t.Errorf("ParseFacilityLevels(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestLevelTable_MostSpecificWins(t *testing.T) {
+	table := newLevelTable(FacilityLevels{
+		"SIP.*":           LevelDebug,
+		"SIP.Transport.*": LevelError,
+		"SIP.Transport":   LevelWarning,
+		"RTP":             LevelWarning,
+		"*":               LevelInfo,
+	})
+	tests := []struct {
+		facility string
+		want     Level
+	}{
+		{"SIP", LevelDebug},
+		{"SIP.Dialog", LevelDebug},
+		{"SIP.Transport", LevelWarning},
+		{"SIP.Transport.TLS", LevelError},
+		{"SIPX", LevelInfo},
+		{"RTP", LevelWarning},
+		{"RTP.Jitter", LevelInfo},
+		{"Application", LevelInfo},
+	}
+	for _, tt := range tests {
+		for i := 0; i < 2; i++ { // second round hits the cache
+			if got := table.min(tt.facility); got != tt.want {
+				t.Errorf("min(%q) = %s, want %s", tt.facility, got, tt.want)
+			}
+		}
+	}
+	if newLevelTable(nil) != nil {
+		t.Error("empty table should compile to nil")
+	}
+}
+
+func TestLevelTable_CacheIsBounded(t *testing.T) {
+	table := newLevelTable(FacilityLevels{"Call.*": LevelWarning, "*": LevelInfo})
+	for i := 0; i < maxLevelCacheEntries+100; i++ {
+		if got := table.min(fmt.Sprintf("Call.%d", i)); got != LevelWarning {
+			t.Fatalf("min(Call.%d) = %s, want WARNING", i, got)
+		}
+	}
+	var n int
+	table.cache.Range(func(_, _ interface{}) bool { n++; return true })
+	if n != maxLevelCacheEntries {
+		t.Errorf("cache holds %d facilities, want %d", n, maxLevelCacheEntries)
+	}
+	if got := table.min("Other"); got != LevelInfo {
+		t.Errorf("min(Other) past the cap = %s, want INFO", got)
+	}
+}
+
+func TestFacilityLevels_FilterBeforeEnqueue(t *testing.T) {
+	hook := &testHook{}
+	cfg := DefaultConfig()
+	cfg.Console.Enabled = false
+	cfg.Dedupe.Enabled = false
+	cfg.Hooks.Global = []Hook{hook}
+	cfg.FacilityLevels = FacilityLevels{"SIP.*": LevelDebug, "RTP": LevelWarning, "*": LevelInfo}
+	l, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	l.Debug("SIP", "sip debug")
+	l.Named("SIP").Debug("Dialog", "dialog debug")
+	l.Info("RTP", "rtp info")
+	l.WarningCtx(context.Background(), "RTP", "rtp warning")
+	l.Debug("HTTP", "http debug")
+	l.Info("HTTP", "http info")
+	slogger := slog.New(NewSlogHandler(l, &SlogHandlerOptions{Iface: "RTP"}))
+	if slogger.Enabled(context.Background(), slog.LevelInfo) {
+		t.Error("slog handler Enabled ignores the facility table")
+	}
+	slogger.Info("rtp via slog")
+
+	if err := l.SetFacilityLevel("RTP", LevelDebug); err != nil {
+		t.Fatalf("SetFacilityLevel: %v", err)
+	}
+	l.Debug("RTP", "rtp debug after set")
+	if err := l.SetFacilityLevel("R*P", LevelDebug); err == nil {
+		t.Error("invalid pattern accepted")
+	}
+	l.Shutdown(context.Background())
+
+	var got []string
+	for _, e := range hook.getEvents() {
+		got = append(got, e.Message)
+	}
+	want := []string{"sip debug", "dialog debug", "rtp warning", "http info", "rtp debug after set"}
+	if len(got) != len(want) {
+		t.Fatalf("delivered %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("delivered %v, want %v", got, want)
+		}
+	}
+	if stats := l.Stats(); stats.AcceptedCount != 5 || stats.DropsPerLevel[LevelDebug] != 0 {
+		t.Errorf("filtered events touched stats: %+v", stats)
+	}
+}
@@ -0,0 +1,269 @@
+// Package clog: structured key/value fields attached to log events.
+package clog
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// FieldKind identifies the type of value a Field carries.
+type FieldKind uint8
+
+const (
+	// FieldString is a string value (redacted before reaching sinks and hooks).
+	FieldString FieldKind = iota
+	// FieldInt is a signed integer value.
+	FieldInt
+	// FieldFloat is a floating-point value.
+	FieldFloat
+	// FieldBool is a boolean value.
+	FieldBool
+	// FieldDuration is a time.Duration value, rendered with Duration.String.
+	FieldDuration
+	// FieldError is an error value, rendered with Error() and redacted.
+	FieldError
+	// FieldAny is an arbitrary value, rendered with %+v and redacted.
+	FieldAny
+)
+
+// Field is a structured key/value pair carried on an Event. Fields travel
+// through the agent alongside the message: string-like values (String, Err,
+// Any) are PII-redacted individually, hooks see them in Event.Fields, the JSON
+// formatter and BetterStack sink emit them as top-level keys, and the console
+// and file sinks append them as key=value suffixes.
+//
+// Build fields with the typed constructors (String, Int, Dur, Err, ...) and
+// pass them among a log call's params; they are separated from the printf
+// arguments on the agent goroutine:
+//
+//	clog.Info("SIP", "call answered after %d rings", rings,
+//		clog.String("call_id", id), clog.Dur("setup", setup))
+type Field struct {
+	Key  string
+	Kind FieldKind
+	str  string
+	num  int64
+	any  interface{}
+}
+
+// String returns a string-valued field.
+func String(key, val string) Field {
+	return Field{Key: key, Kind: FieldString, str: val}
+}
+
+// Int returns an int-valued field.
+func Int(key string, val int) Field {
+	return Field{Key: key, Kind: FieldInt, num: int64(val)}
+}
+
+// Int64 returns an int64-valued field.
+func Int64(key string, val int64) Field {
+	return Field{Key: key, Kind: FieldInt, num: val}
+}
+
+// Float64 returns a float64-valued field.
+func Float64(key string, val float64) Field {
+	return Field{Key: key, Kind: FieldFloat, num: int64(math.Float64bits(val))}
+}
+
+// Bool returns a bool-valued field.
+func Bool(key string, val bool) Field {
+	var n int64
+	if val {
+		n = 1
+	}
+	return Field{Key: key, Kind: FieldBool, num: n}
+}
+
+// Dur returns a time.Duration-valued field.
+func Dur(key string, val time.Duration) Field {
+	return Field{Key: key, Kind: FieldDuration, num: int64(val)}
+}
+
+// Err returns an error field under the key "error". A nil error yields an
+// empty string value.
+func Err(err error) Field {
+	return Field{Key: "error", Kind: FieldError, any: err}
+}
+
+// Any returns a field for an arbitrary value. Common scalar types are mapped
+// onto their typed constructors; anything else is rendered with %+v.
+func Any(key string, val interface{}) Field {
+	switch v := val.(type) {
+	case string:
+		return String(key, v)
+	case int:
+		return Int(key, v)
+	case int64:
+		return Int64(key, v)
+	case int32:
+		return Int64(key, int64(v))
+	case float64:
+		return Float64(key, v)
+	case bool:
+		return Bool(key, v)
+	case time.Duration:
+		return Dur(key, v)
+	case error:
+		return Field{Key: key, Kind: FieldError, any: v}
+	default:
+		return Field{Key: key, Kind: FieldAny, any: val}
+	}
+}
+
+// Value returns the field's value as a Go value (string, int64, float64,
+// bool, time.Duration, error, or the original value for Any).
+func (f Field) Value() interface{} {
+	switch f.Kind {
+	case FieldString:
+		return f.str
+	case FieldInt:
+		return f.num
+	case FieldFloat:
+		return math.Float64frombits(uint64(f.num))
+	case FieldBool:
+		return f.num != 0
+	case FieldDuration:
+		return time.Duration(f.num)
+	default:
+		return f.any
+	}
+}
+
+// String returns the field's value rendered as text, as the console and file
+// sinks print it.
+func (f Field) String() string {
+	switch f.Kind {
+	case FieldString:
+		return f.str
+	case FieldInt:
+		return strconv.FormatInt(f.num, 10)
+	case FieldFloat:
+		return strconv.FormatFloat(math.Float64frombits(uint64(f.num)), 'g', -1, 64)
+	case FieldBool:
+		return strconv.FormatBool(f.num != 0)
+	case FieldDuration:
+		return time.Duration(f.num).String()
+	case FieldError:
+		if f.any == nil {
+			return ""
+		}
+		return errorText(f.any.(error))
+	default:
+		// fmt recovers a panicking String or Error method.
+		return fmt.Sprintf("%+v", f.any)
+	}
+}
+
+// errorText returns err.Error(), recovering a panic the way fmt does: a nil
+// pointer receiver renders as "<nil>", any other panic as
+// "%!v(PANIC=Error method: ...)". It runs on the agent goroutine, where a
+// typed-nil error such as Err((*MyErr)(nil)) must not crash the process.
+func errorText(err error) (s string) {
+	defer func() {
+		if p := recover(); p != nil {
+			if v := reflect.ValueOf(err); v.Kind() == reflect.Pointer && v.IsNil() {
+				s = "<nil>"
+				return
+			}
+			s = fmt.Sprintf("%%!v(PANIC=Error method: %v)", p)
+		}
+	}()
+	return err.Error()
+}
+
+// redacted returns a copy of f whose text is PII-redacted by redact. String,
+// error and arbitrary values are rendered and scrubbed into a FieldString;
+// numeric and boolean fields carry no free text and are returned unchanged.
+func (f Field) redacted(redact func(string) string) Field {
+	switch f.Kind {
+	case FieldString, FieldError, FieldAny:
+		return Field{Key: f.Key, Kind: FieldString, str: redact(f.String())}
+	default:
+		return f
+	}
+}
+
+// splitFields separates Field values from printf params. The caller's slice
+// is never mutated; when params contain no Field the original slice is
+// returned with a nil field list (no allocation, common case).
+func splitFields(params []interface{}) ([]interface{}, []Field) {
+	n := 0
+	for _, p := range params {
+		if _, ok := p.(Field); ok {
+			n++
+		}
+	}
+	if n == 0 {
+		return params, nil
+	}
+	args := make([]interface{}, 0, len(params)-n)
+	fields := make([]Field, 0, n)
+	for _, p := range params {
+		if f, ok := p.(Field); ok {
+			fields = append(fields, f)
+		} else {
+			args = append(args, p)
+		}
+	}
+	return args, fields
+}
+
+// appendFieldsText appends fields as " key=value" pairs. Values containing
+// spaces, quotes, '=' or control characters are quoted so the suffix stays
+// machine-splittable.
+func appendFieldsText(b []byte, fields []Field) []byte {
+	for _, f := range fields {
+		b = append(b, ' ')
+		b = append(b, f.Key...)
+		b = append(b, '=')
+		// Numbers and booleans never need quoting; append them in place.
+		switch f.Kind {
+		case FieldInt:
+			b = strconv.AppendInt(b, f.num, 10)
+			continue
+		case FieldFloat:
+			b = strconv.AppendFloat(b, math.Float64frombits(uint64(f.num)), 'g', -1, 64)
+			continue
+		case FieldBool:
+			b = strconv.AppendBool(b, f.num != 0)
+			continue
+		}
+		v := f.String()
+		if needsQuote(v) {
+			b = strconv.AppendQuote(b, v)
+		} else {
+			b = append(b, v...)
+		}
+	}
+	return b
+}
+
+// appendEventSuffix appends an event's fields and, when captured, its caller
+// as " key=value" pairs: the text form shared by the console and file sinks and
+// plain Sinks.
+func appendEventSuffix(b []byte, e Event) []byte {
+	b = appendFieldsText(b, e.Fields)
+	if e.Caller != "" {
+		b = append(b, " caller="...)
+		b = append(b, e.Caller...)
+	}
+	return b
+}
+
+// needsQuote reports whether a text-rendered field value must be quoted.
+func needsQuote(s string) bool {
+	if s == "" {
+		return true
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c <= ' ' || c == '"' || c == '=' || c == 0x7f {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,228 @@
+package clog
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestField_String(t *testing.T) {
+	tests := []struct {
+		field Field
+		want  string
+	}{
+		{String("k", "v"), "v"},
+		{Int("k", -42), "-42"},
+		{Int64("k", 1<<40), "1099511627776"},
+		{Float64("k", 1.5), "1.5"},
+		{Bool("k", true), "true"},
+		{Dur("k", 1500*time.Millisecond), "1.5s"},
+		{Err(errors.New("boom")), "boom"},
+		{Err(nil), ""},
+		{Any("k", struct{ A int }{7}), "{A:7}"},
+	}
+	for _, tt := range tests {
+		if got := tt.field.String(); got != tt.want {
+			t.Errorf("Field(%v).String() = %q, want %q", tt.field.Kind, got, tt.want)
+		}
+	}
+}
+
+func TestAny_MapsScalarKinds(t *testing.T) {
+	tests := []struct {
+		val  interface{}
+		kind FieldKind
+	}{
+		{"s", FieldString},
+		{3, FieldInt},
+		{int64(3), FieldInt},
+		{2.5, FieldFloat},
+		{false, FieldBool},
+		{time.Second, FieldDuration},
+		{errors.New("e"), FieldError},
+		{[]int{1}, FieldAny},
+	}
+	for _, tt := range tests {
+		if got := Any("k", tt.val).Kind; got != tt.kind {
+			t.Errorf("Any(%T).Kind = %v, want %v", tt.val, got, tt.kind)
+		}
+	}
+}
+
+func TestSplitFields(t *testing.T) {
+	params := []interface{}{1, String("call_id", "c1"), "x", Int("n", 2)}
+	args, fields := splitFields(params)
+	if len(args) != 2 || args[0] != 1 || args[1] != "x" {
+		t.Errorf("args = %v, want [1 x]", args)
+	}
+	if len(fields) != 2 || fields[0].Key != "call_id" || fields[1].Key != "n" {
+		t.Errorf("fields = %v, want call_id, n", fields)
+	}
+	if _, ok := params[1].(Field); !ok {
+		t.Error("splitFields mutated the caller's params")
+	}
+
+	plain := []interface{}{1, 2}
+	args, fields = splitFields(plain)
+	if &args[0] != &plain[0] || fields != nil {
+		t.Error("splitFields should return params unchanged when there are no fields")
+	}
+}
+
+func TestAppendFieldsText_Quoting(t *testing.T) {
+	got := string(appendFieldsText(nil, []Field{
+		String("a", "plain"),
+		String("b", "two words"),
+		String("c", ""),
+		String("d", `x="y"`),
+		Int("e", 5),
+	}))
+	want := ` a=plain b="two words" c="" d="x=\"y\"" e=5`
+	if got != want {
+		t.Errorf("appendFieldsText = %q, want %q", got, want)
+	}
+}
+
+// TestFieldsThroughAgent proves fields travel through processEvent: hooks and
+// EventSinks see them redacted per-field, and plain Sinks receive them as a
+// key=value suffix.
+func TestFieldsThroughAgent(t *testing.T) {
+	prevEnabled := RedactionEnabled()
+	defer SetRedactionEnabled(prevEnabled)
+	SetRedactionEnabled(true)
+
+	hook := &captureHook{}
+	sink := &captureSink{}
+	cfg := DefaultConfig()
+	cfg.Console.Enabled = false
+	cfg.Dedupe.Enabled = false
+	cfg.Hooks.Global = []Hook{hook}
+
+	a, err := newAgent(cfg)
+	if err != nil {
+		t.Fatalf("newAgent: %v", err)
+	}
+	a.sinks = append(a.sinks, sink)
+
+	a.enqueue(Event{
+		Level:   LevelInfo,
+		Iface:   "SIP",
+		Message: "call %s answered",
+		Params: []interface{}{
+			"c-1",
+			String("participant_id", "358401234567@10.0.0.19"),
+			Int("duration_ms", 1200),
+			Err(errors.New("peer alice@example.com hung up")),
+		},
+	})
+	a.stop(context.Background())
+
+	events := hook.snapshot()
+	if len(events) != 1 {
+		t.Fatalf("hook got %d events, want 1", len(events))
+	}
+	e := events[0]
+	if e.Message != "call c-1 answered" {
+		t.Errorf("hook Message = %q", e.Message)
+	}
+	if len(e.Fields) != 3 {
+		t.Fatalf("hook Fields = %v, want 3 fields", e.Fields)
+	}
+	if got := e.Fields[0].String(); got != "<phone>@<ip>" {
+		t.Errorf("participant_id field = %q, want redacted", got)
+	}
+	if got := e.Fields[1].Value(); got != int64(1200) {
+		t.Errorf("duration_ms field = %v, want 1200", got)
+	}
+	if got := e.Fields[2].String(); got != "peer <email> hung up" {
+		t.Errorf("error field = %q, want redacted", got)
+	}
+
+	msgs := sink.snapshot()
+	if len(msgs) != 1 {
+		t.Fatalf("sink got %d messages, want 1", len(msgs))
+	}
+	want := `call c-1 answered participant_id=<phone>@<ip> duration_ms=1200 error="peer <email> hung up"`
+	if msgs[0] != want {
+		t.Errorf("plain sink got %q, want %q", msgs[0], want)
+	}
+	if strings.Contains(msgs[0], "358401234567") {
+		t.Error("raw PII leaked to plain sink via fields")
+	}
+}
+
+// TestDedupe_DistinctFieldsNotCollapsed proves two events with the same message
+// but different field values are both emitted.
+func TestDedupe_DistinctFieldsNotCollapsed(t *testing.T) {
+	sink := &captureSink{}
+	cfg := DefaultConfig()
+	cfg.Console.Enabled = false
+	a, err := newAgent(cfg)
+	if err != nil {
+		t.Fatalf("newAgent: %v", err)
+	}
+	a.sinks = append(a.sinks, sink)
+
+	a.enqueue(Event{Level: LevelInfo, Iface: "SIP", Message: "ringing", Params: []interface{}{String("call_id", "a")}})
+	a.enqueue(Event{Level: LevelInfo, Iface: "SIP", Message: "ringing", Params: []interface{}{String("call_id", "b")}})
+	a.stop(context.Background())
+
+	if got := sink.snapshot(); len(got) != 2 {
+		t.Errorf("sink got %v, want both events", got)
+	}
+}
+
+type ptrErr struct{ msg string }
+
+func (e *ptrErr) Error() string { return e.msg }
+
+type ptrStringer struct{ s string }
+
+func (p *ptrStringer) String() string { return p.s }
+
+type panicStringer struct{}
+
+func (panicStringer) String() string { panic("no name") }
+
+func TestField_StringRecoversPanickingMethods(t *testing.T) {
+	tests := []struct {
+		field Field
+		want  string
+	}{
+		{Err((*ptrErr)(nil)), "<nil>"},
+		{Any("k", (*ptrErr)(nil)), "<nil>"},
+		{Err(&ptrErr{}), ""},
+		{Any("k", (*ptrStringer)(nil)), "<nil>"},
+		{Any("k", panicStringer{}), "%!v(PANIC=String method: no name)"},
+	}
+	for _, tt := range tests {
+		if got := tt.field.String(); got != tt.want {
+			t.Errorf("Field(%v).String() = %q, want %q", tt.field.Kind, got, tt.want)
+		}
+	}
+	if got := Err(errPanicky{}).String(); got != "%!v(PANIC=Error method: broken)" {
+		t.Errorf("panicking Error method rendered as %q", got)
+	}
+
+	// Through the agent: the process survives and the line is written.
+	hook := &recordHook{}
+	cfg := panicsConfig(true)
+	cfg.Hooks.Global = []Hook{hook}
+	l, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer l.Shutdown(context.Background())
+	var nilErr *ptrErr
+	l.Error("SIP", "failed", Err(nilErr))
+	l.Info("SIP", "still logging")
+	if got := hook.snapshot(); len(got) != 2 {
+		t.Errorf("hook got %q, want both events", got)
+	}
+}
+
+type errPanicky struct{}
+
+func (errPanicky) Error() string { panic("broken") }
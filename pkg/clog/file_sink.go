@@ -2,20 +2,18 @@
 package clog
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"sync"
-	"time"
-
-	"github.com/LastBotInc/coralie-logging-go/internal/timefmt"
 )
 
 // fileSink handles file output with per-level routing.
 type fileSink struct {
-	cfg      FileConfig
-	files    map[Level]*os.File
-	mu       sync.Mutex
+	cfg   FileConfig
+	files map[Level]*os.File
+	mu    sync.Mutex
 }
 
 // newFileSink creates a new file sink.
@@ -44,7 +42,7 @@ func newFileSink(cfg FileConfig) (*fileSink, error) {
 			file, err := os.OpenFile(filepath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600) //nolint:gosec // filepath is constructed from config, not user input
 			if err != nil {
 				// Close already opened files
-				s.close()
+				_ = s.close()
 				return nil, fmt.Errorf("failed to open log file %s: %w", filepath, err)
 			}
 			s.files[level] = file
@@ -54,67 +52,115 @@ func newFileSink(cfg FileConfig) (*fileSink, error) {
 	return s, nil
 }
 
-// write writes a formatted message to the appropriate file(s).
-func (s *fileSink) write(level Level, iface, formatted string) {
+// write writes an event to the appropriate file(s).
+func (s *fileSink) write(e Event) error {
 	if s == nil {
-		return
+		return nil
 	}
 
+	// Format: [<timestamp>][<level>][<facility>]<message> key=value...
+	buf := getBuffer()
+	defer buf.free()
+	buf.b = appendTextLine(buf.b, e, false)
+	return s.writeLine(e.Level, e.Iface, buf.b)
+}
+
+// lineFormat implements lineSink.
+func (s *fileSink) lineFormat() lineFormat {
+	return lineText
+}
+
+// writeLine implements lineSink: it writes the text line to the level's file.
+func (s *fileSink) writeLine(level Level, _ string, line []byte) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	file, ok := s.files[level]
 	if !ok {
-		return // Level not configured for file output
+		return nil // Level not configured for file output
 	}
-
-	// Format: [<timestamp>][<level>][<facility>]<message>
-	now := time.Now()
-	timestamp := timefmt.Format(now, "")
-	output := fmt.Sprintf("[%s][%s][%s]%s\n", timestamp, level.String(), iface, formatted)
-	_, _ = file.WriteString(output)
+	_, err := file.Write(line)
+	return err
 }
 
-// flush flushes all open files.
-func (s *fileSink) flush() {
+// flush syncs all open files and returns their errors joined.
+func (s *fileSink) flush() error {
 	if s == nil {
-		return
+		return nil
 	}
 
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	var errs []error
 	for _, file := range s.files {
-		_ = file.Sync()
+		if err := file.Sync(); err != nil {
+			errs = append(errs, err)
+		}
 	}
+	return errors.Join(errs...)
 }
 
-// close closes all open files.
-func (s *fileSink) close() {
+// close closes all open files and returns their errors joined.
+func (s *fileSink) close() error {
 	if s == nil {
-		return
+		return nil
 	}
 
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	var errs []error
 	for level, file := range s.files {
-		_ = file.Close()
+		if err := file.Close(); err != nil {
+			errs = append(errs, err)
+		}
 		delete(s.files, level)
 	}
+	return errors.Join(errs...)
 }
 
 // Write implements Sink. Writes a formatted message to the appropriate file(s).
 func (s *fileSink) Write(level Level, iface, formatted string) {
-	s.write(level, iface, formatted)
+	_ = s.write(Event{Level: level, Iface: iface, Message: formatted})
+}
+
+// WriteEvent implements EventSink. Writes the message and its fields to the
+// appropriate file(s).
+func (s *fileSink) WriteEvent(e Event) {
+	_ = s.write(e)
+}
+
+// WriteErr implements ErrorWriter.
+func (s *fileSink) WriteErr(e Event) error {
+	return s.write(e)
+}
+
+// Enabled implements LevelEnabler: only levels with a configured file are
+// written.
+func (s *fileSink) Enabled(level Level, _ string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.files[level]
+	return ok
 }
 
 // Flush implements Sink. Syncs all open files.
 func (s *fileSink) Flush() {
-	s.flush()
+	_ = s.flush()
+}
+
+// FlushErr implements ErrorFlusher.
+func (s *fileSink) FlushErr() error {
+	return s.flush()
 }
 
 // Close implements Sink. Closes all open files.
 func (s *fileSink) Close() {
-	s.close()
+	_ = s.close()
+}
+
+// CloseErr implements ErrorCloser.
+func (s *fileSink) CloseErr() error {
+	return s.close()
 }
@@ -0,0 +1,139 @@
+// Package clog: flush barrier that keeps the logger running.
+package clog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// FlushError reports the sinks that did not flush cleanly. Failed maps a sink
+// name (as in Stats.Sinks, or "audio" for the audio writer) to the error its
+// flush returned; TimedOut lists sinks still flushing when the context ended.
+type FlushError struct {
+	Failed   map[string]error
+	TimedOut []string
+	// Err is the context's error when TimedOut is not empty.
+	Err error
+}
+
+// Error implements error.
+func (e *FlushError) Error() string {
+	var parts []string
+	names := make([]string, 0, len(e.Failed))
+	for name := range e.Failed {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		parts = append(parts, fmt.Sprintf("%s: %v", name, e.Failed[name]))
+	}
+	if len(e.TimedOut) > 0 {
+		parts = append(parts, fmt.Sprintf("timed out: %s", strings.Join(e.TimedOut, ", ")))
+	}
+	return "flush failed: " + strings.Join(parts, "; ")
+}
+
+// Unwrap returns the sink errors and, on timeout, the context error, so
+// errors.Is(err, context.DeadlineExceeded) works.
+func (e *FlushError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Failed)+1)
+	for _, err := range e.Failed {
+		errs = append(errs, err)
+	}
+	if e.Err != nil {
+		errs = append(errs, e.Err)
+	}
+	return errs
+}
+
+// Flush flushes the default logger. See Logger.Flush.
+func Flush(ctx context.Context) error {
+	return Default().Flush(ctx)
+}
+
+// Flush waits until every event logged before the call has been written by
+// every sink, then flushes each sink and the audio writer, without shutting
+// the logger down. It inserts a barrier into the queue, so events logged
+// concurrently or afterwards do not delay it beyond their place in line.
+// It returns nil, ErrNotRunning, ErrReentrant (when called from a Hook or
+// OnSinkError), or a *FlushError naming the sinks that failed or had not
+// finished when ctx ended. Pending dedupe repeat counts are
+// not flushed; they are still reported when the run of duplicates ends.
+func (l *Logger) Flush(ctx context.Context) error {
+	if l == nil {
+		return ErrNotRunning
+	}
+	return l.agent.flush(ctx)
+}
+
+// pendingFlush is one sink's outstanding flush request.
+type pendingFlush struct {
+	name    string
+	sink    *asyncSink
+	flushed chan error
+	err     error // set when the request could not be queued
+	done    bool  // set for sinks flushed inline by the control request
+}
+
+// flush implements Logger.Flush.
+func (a *agent) flush(ctx context.Context) error {
+	if a.own.current() {
+		return ErrReentrant
+	}
+	var pending []pendingFlush
+	c := &control{done: make(chan struct{})}
+	c.apply = func() {
+		// On the agent goroutine, every earlier event has been handed to the
+		// sinks; ask each sink to flush once it has written them. This never
+		// waits on a sink, so a hung one cannot stall the agent.
+		for i, s := range a.sinks {
+			switch s := s.(type) {
+			case *asyncSink:
+				flushed, err := s.requestFlush()
+				pending = append(pending, pendingFlush{name: s.name, sink: s, flushed: flushed, err: err})
+			case *syncSink:
+				pending = append(pending, pendingFlush{name: s.name, err: s.flush(), done: true})
+			default:
+				pending = append(pending, pendingFlush{name: fmt.Sprintf("sink#%d", i+1), err: flushSink(s), done: true})
+			}
+		}
+	}
+	if err := a.runControl(ctx, c); err != nil {
+		if errors.Is(err, ErrNotRunning) {
+			return err
+		}
+		return &FlushError{TimedOut: []string{"queue"}, Err: err}
+	}
+
+	fe := &FlushError{Failed: map[string]error{}}
+	for _, p := range pending {
+		err := p.err
+		if err == nil && !p.done {
+			err = p.sink.awaitFlush(ctx, p.flushed)
+		}
+		switch {
+		case err == nil:
+		case ctx.Err() != nil && errors.Is(err, ctx.Err()):
+			fe.TimedOut = append(fe.TimedOut, p.name)
+			fe.Err = err
+		default:
+			fe.Failed[p.name] = err
+		}
+	}
+
+	a.audioMu.RLock()
+	if a.audioWriter != nil {
+		if err := a.audioWriter.Flush(); err != nil {
+			fe.Failed["audio"] = err
+		}
+	}
+	a.audioMu.RUnlock()
+
+	if len(fe.Failed) == 0 && len(fe.TimedOut) == 0 {
+		return nil
+	}
+	return fe
+}
@@ -0,0 +1,119 @@
+package clog
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// failingFlushSink is a captureSink whose FlushErr always fails.
+type failingFlushSink struct {
+	captureSink
+}
+
+func (s *failingFlushSink) FlushErr() error { return errors.New("disk full") }
+
+func TestFlush_WritesEverythingWithoutShutdown(t *testing.T) {
+	dir := t.TempDir()
+	l, err := New(fileOnlyConfig(dir, &testHook{}))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer l.Shutdown(context.Background())
+
+	for i := 0; i < 100; i++ {
+		l.Info("Test", "event %d", i)
+	}
+	if err := l.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	if n := strings.Count(readLog(t, dir), "\n"); n != 100 {
+		t.Errorf("file has %d lines after Flush, want 100", n)
+	}
+
+	l.Info("Test", "still running")
+	if err := l.Flush(context.Background()); err != nil {
+		t.Fatalf("second Flush: %v", err)
+	}
+	if !strings.Contains(readLog(t, dir), "still running") {
+		t.Error("logger stopped after Flush")
+	}
+}
+
+func TestFlush_ReportsFailedAndTimedOutSinks(t *testing.T) {
+	stuck := newStuckSink()
+	a := newSinkTestAgent(t,
+		namedSink{name: "stuck", sink: stuck},
+		namedSink{name: "bad", sink: &failingFlushSink{}},
+		namedSink{name: "good", sink: &captureSink{}},
+	)
+	l := &Logger{agent: a}
+
+	l.Info("Test", "blocks the stuck sink")
+	<-stuck.entered
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := l.Flush(ctx)
+
+	var fe *FlushError
+	if !errors.As(err, &fe) {
+		t.Fatalf("Flush error = %v, want *FlushError", err)
+	}
+	if len(fe.TimedOut) != 1 || fe.TimedOut[0] != "stuck" {
+		t.Errorf("TimedOut = %v, want [stuck]", fe.TimedOut)
+	}
+	if len(fe.Failed) != 1 || fe.Failed["bad"] == nil {
+		t.Errorf("Failed = %v, want bad only", fe.Failed)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("errors.Is(err, DeadlineExceeded) = false for %v", err)
+	}
+
+	close(stuck.release)
+	l.Shutdown(context.Background())
+	if err := l.Flush(context.Background()); !errors.Is(err, ErrNotRunning) {
+		t.Errorf("Flush after Shutdown = %v, want ErrNotRunning", err)
+	}
+}
+
+func TestFlush_HungSinkWithFullBufferDoesNotStallAgent(t *testing.T) {
+	stuck := newStuckSink()
+	good := &captureSink{}
+	a := newSinkTestAgent(t,
+		namedSink{name: "stuck", sink: stuck, delivery: DeliveryConfig{BufferSize: 1}},
+		namedSink{name: "good", sink: good},
+	)
+	l := &Logger{agent: a}
+
+	l.Info("Test", "blocks the stuck sink")
+	<-stuck.entered
+	l.Info("Test", "fills its buffer")
+
+	flushed := make(chan error, 1)
+	go func() { flushed <- l.Flush(context.Background()) }()
+
+	l.Info("Test", "after flush")
+	deadline := time.Now().Add(2 * time.Second)
+	for len(good.snapshot()) < 3 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if got := good.snapshot(); len(got) != 3 || got[2] != "after flush" {
+		t.Fatalf("good sink got %q while the stuck sink held a flush, want all 3 events", got)
+	}
+	select {
+	case err := <-flushed:
+		t.Fatalf("Flush returned %v before the stuck sink wrote its events", err)
+	default:
+	}
+
+	close(stuck.release)
+	if err := <-flushed; err != nil {
+		t.Errorf("Flush = %v once the sink recovered", err)
+	}
+	if got := stuck.snapshot(); len(got) < 2 {
+		t.Errorf("stuck sink wrote %q before Flush returned", got)
+	}
+	l.Shutdown(context.Background())
+}
@@ -2,48 +2,85 @@
 package clog
 
 import (
-	"encoding/json"
-	"fmt"
 	"time"
 
 	"github.com/LastBotInc/coralie-logging-go/internal/timefmt"
 )
 
 // Formatter formats a log event for a sink. All built-in sinks can use
-// a formatter to change output format (e.g. text vs JSON).
+// a formatter to change output format (e.g. text vs JSON). t is the event's
+// call-site timestamp (Event.Time).
 type Formatter interface {
 	Format(level Level, iface, formatted string, t time.Time) []byte
 }
 
+// EventFormatter is implemented by formatters that can render a whole
+// processed Event, structured fields and timestamp included. TextFormatter
+// and JSONFormatter implement it.
+type EventFormatter interface {
+	FormatEvent(e Event) []byte
+}
+
 // TextFormatter produces human-readable lines: [timestamp][level][facility]message
-// (same style as the file sink). No color.
-type TextFormatter struct{}
+// (same style as the file sink), followed by any fields as key=value pairs. No color.
+type TextFormatter struct {
+	// ShortLevels writes the level's ShortString form (WRN) instead of its
+	// full name (WARNING), for compact output.
+	ShortLevels bool
+}
 
 // Format implements Formatter.
-func (TextFormatter) Format(level Level, iface, formatted string, t time.Time) []byte {
-	ts := timefmt.Format(t, "")
-	return []byte(fmt.Sprintf("[%s][%s][%s]%s\n", ts, level.String(), iface, formatted))
+func (f TextFormatter) Format(level Level, iface, formatted string, t time.Time) []byte {
+	return f.FormatEvent(Event{Level: level, Iface: iface, Message: formatted, Time: t})
+}
+
+// FormatEvent implements EventFormatter.
+func (f TextFormatter) FormatEvent(e Event) []byte {
+	return appendTextLine(nil, e, f.ShortLevels)
+}
+
+// appendTextLine appends "[ts][LEVEL][facility]message k=v caller=...\n" to
+// b, with the short level form when short is set.
+func appendTextLine(b []byte, e Event, short bool) []byte {
+	b = append(b, '[')
+	b = timefmt.Append(b, eventTime(e), "")
+	b = append(b, "]["...)
+	if short {
+		b = append(b, e.Level.ShortString()...)
+	} else {
+		b = append(b, e.Level.String()...)
+	}
+	b = append(b, "]["...)
+	b = append(b, e.Iface...)
+	b = append(b, ']')
+	b = append(b, e.Message...)
+	b = appendEventSuffix(b, e)
+	return append(b, '\n')
 }
 
 // JSONFormatter produces one JSON object per event for machine consumption
-// (e.g. BetterStack). Fields: dt (RFC3339), level, facility, message.
+// (e.g. BetterStack). Fields: dt (RFC3339, the call-site Event.Time), level,
+// facility, message, seq (when set), caller (when captured), plus one
+// top-level key per structured field. A field whose key collides with one of
+// the fixed keys is emitted as "fields.<key>".
 type JSONFormatter struct{}
 
-type jsonEvent struct {
-	Dt       string `json:"dt"`
-	Level    string `json:"level"`
-	Facility string `json:"facility"`
-	Message  string `json:"message"`
+// Format implements Formatter.
+func (f JSONFormatter) Format(level Level, iface, formatted string, t time.Time) []byte {
+	return f.FormatEvent(Event{Level: level, Iface: iface, Message: formatted, Time: t})
 }
 
-// Format implements Formatter.
-func (JSONFormatter) Format(level Level, iface, formatted string, t time.Time) []byte {
-	ev := jsonEvent{
-		Dt:       t.UTC().Format(time.RFC3339Nano),
-		Level:    level.String(),
-		Facility: iface,
-		Message:  formatted,
-	}
-	b, _ := json.Marshal(ev)
+// FormatEvent implements EventFormatter.
+func (JSONFormatter) FormatEvent(e Event) []byte {
+	b := appendJSONEvent(nil, e)
 	return append(b, '\n')
 }
+
+// eventTime returns the event's call-site timestamp, or the current time for
+// events that never went through a Logger (e.g. a direct Sink.Write call).
+func eventTime(e Event) time.Time {
+	if e.Time.IsZero() {
+		return time.Now()
+	}
+	return e.Time
+}
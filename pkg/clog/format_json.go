@@ -0,0 +1,116 @@
+// Package clog: hand-rolled JSON encoding of events with dynamic field keys.
+package clog
+
+import (
+	"math"
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+// reservedJSONKeys are the fixed keys of every JSON event; structured fields
+// with these keys are renamed to "fields.<key>" so they cannot shadow them.
+var reservedJSONKeys = map[string]bool{
+	"dt":       true,
+	"level":    true,
+	"facility": true,
+	"message":  true,
+	"seq":      true,
+	"caller":   true,
+}
+
+// appendJSONEvent appends e as a single JSON object (no trailing newline).
+func appendJSONEvent(b []byte, e Event) []byte {
+	b = append(b, `{"dt":`...)
+	b = appendJSONString(b, eventTime(e).UTC().Format(time.RFC3339Nano))
+	b = append(b, `,"level":`...)
+	b = appendJSONString(b, e.Level.String())
+	b = append(b, `,"facility":`...)
+	b = appendJSONString(b, e.Iface)
+	b = append(b, `,"message":`...)
+	b = appendJSONString(b, e.Message)
+	if e.Seq != 0 {
+		b = append(b, `,"seq":`...)
+		b = strconv.AppendUint(b, e.Seq, 10)
+	}
+	if e.Caller != "" {
+		b = append(b, `,"caller":`...)
+		b = appendJSONString(b, e.Caller)
+	}
+	for _, f := range e.Fields {
+		b = append(b, ',')
+		key := f.Key
+		if reservedJSONKeys[key] {
+			key = "fields." + key
+		}
+		b = appendJSONString(b, key)
+		b = append(b, ':')
+		b = appendJSONValue(b, f)
+	}
+	return append(b, '}')
+}
+
+// appendJSONValue appends a field's value: numbers and booleans natively,
+// everything else (durations included) as a JSON string.
+func appendJSONValue(b []byte, f Field) []byte {
+	switch f.Kind {
+	case FieldInt:
+		return strconv.AppendInt(b, f.num, 10)
+	case FieldFloat:
+		v := math.Float64frombits(uint64(f.num))
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return appendJSONString(b, f.String())
+		}
+		return strconv.AppendFloat(b, v, 'g', -1, 64)
+	case FieldBool:
+		return strconv.AppendBool(b, f.num != 0)
+	default:
+		return appendJSONString(b, f.String())
+	}
+}
+
+const hexDigits = "0123456789abcdef"
+
+// appendJSONString appends s as a quoted JSON string. Unlike encoding/json it
+// does not HTML-escape, so redaction tokens such as <ip> stay readable in
+// ingest UIs. Invalid UTF-8 is replaced with U+FFFD.
+func appendJSONString(b []byte, s string) []byte {
+	b = append(b, '"')
+	start := 0
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c < utf8.RuneSelf {
+			if c >= 0x20 && c != '"' && c != '\\' {
+				i++
+				continue
+			}
+			b = append(b, s[start:i]...)
+			switch c {
+			case '"', '\\':
+				b = append(b, '\\', c)
+			case '\n':
+				b = append(b, '\\', 'n')
+			case '\r':
+				b = append(b, '\\', 'r')
+			case '\t':
+				b = append(b, '\\', 't')
+			default:
+				b = append(b, '\\', 'u', '0', '0', hexDigits[c>>4], hexDigits[c&0xf])
+			}
+			i++
+			start = i
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			b = append(b, s[start:i]...)
+			b = append(b, `�`...)
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	b = append(b, s[start:]...)
+	return append(b, '"')
+}
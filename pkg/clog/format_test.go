@@ -7,6 +7,14 @@ import (
 	"time"
 )
 
+// jsonLine is the fixed part of a JSON event, as decoded by tests.
+type jsonLine struct {
+	Dt       string `json:"dt"`
+	Level    string `json:"level"`
+	Facility string `json:"facility"`
+	Message  string `json:"message"`
+}
+
 func TestTextFormatter_Format(t *testing.T) {
 	f := TextFormatter{}
 	tm := time.Date(2025, 3, 4, 14, 5, 6, 0, time.UTC)
@@ -30,7 +38,7 @@ func TestJSONFormatter_Format(t *testing.T) {
 	f := JSONFormatter{}
 	tm := time.Date(2025, 3, 4, 14, 5, 6, 0, time.UTC)
 	b := f.Format(LevelError, "Facility", "error message", tm)
-	var ev jsonEvent
+	var ev jsonLine
 	if err := json.Unmarshal(b, &ev); err != nil {
 		t.Fatalf("invalid JSON: %v", err)
 	}
@@ -47,3 +55,69 @@ func TestJSONFormatter_Format(t *testing.T) {
 		t.Errorf("Dt = %q, want RFC3339 date", ev.Dt)
 	}
 }
+
+func TestTextFormatter_FormatEvent_Fields(t *testing.T) {
+	f := TextFormatter{}
+	tm := time.Date(2025, 3, 4, 14, 5, 6, 0, time.UTC)
+	b := f.FormatEvent(Event{
+		Level:   LevelInfo,
+		Iface:   "SIP",
+		Message: "call started",
+		Fields:  []Field{String("call_id", "abc"), Int("duration_ms", 12)},
+		Time:    tm,
+	})
+	s := string(b)
+	if !strings.HasSuffix(s, "]call started call_id=abc duration_ms=12\n") {
+		t.Errorf("unexpected text output %q", s)
+	}
+}
+
+func TestJSONFormatter_FormatEvent_Fields(t *testing.T) {
+	f := JSONFormatter{}
+	tm := time.Date(2025, 3, 4, 14, 5, 6, 0, time.UTC)
+	b := f.FormatEvent(Event{
+		Level:   LevelWarning,
+		Iface:   "RTP",
+		Message: "jitter <ip> \"high\"\n",
+		Fields: []Field{
+			String("call_id", "abc"),
+			Int("duration_ms", 12),
+			Bool("muted", true),
+			Float64("loss", 0.25),
+			Dur("window", 2*time.Second),
+			String("level", "shadowed"),
+		},
+		Time: tm,
+	})
+	var m map[string]interface{}
+	if err := json.Unmarshal(b, &m); err != nil {
+		t.Fatalf("invalid JSON %q: %v", b, err)
+	}
+	want := map[string]interface{}{
+		"level":        "WARNING",
+		"facility":     "RTP",
+		"message":      "jitter <ip> \"high\"\n",
+		"call_id":      "abc",
+		"duration_ms":  float64(12),
+		"muted":        true,
+		"loss":         0.25,
+		"window":       "2s",
+		"fields.level": "shadowed",
+	}
+	for k, v := range want {
+		if m[k] != v {
+			t.Errorf("%s = %#v, want %#v", k, m[k], v)
+		}
+	}
+	if strings.Contains(string(b), `\u003c`) {
+		t.Errorf("JSON output should not HTML-escape: %s", b)
+	}
+}
+
+func TestTextFormatter_ShortLevels(t *testing.T) {
+	tm := time.Date(2025, 3, 4, 14, 5, 6, 0, time.UTC)
+	b := TextFormatter{ShortLevels: true}.Format(LevelWarning, "SIP", "slow", tm)
+	if s := string(b); !strings.Contains(s, "][WRN][SIP]slow") {
+		t.Errorf("got %q, want the short level", s)
+	}
+}
@@ -2,6 +2,8 @@
 package clog
 
 // Hook is an interface for custom log processing.
+// OnLog runs on the logger's agent goroutine. It may log through the same
+// logger, but Reconfigure and Flush called from it return ErrReentrant.
 type Hook interface {
 	OnLog(Event)
 }
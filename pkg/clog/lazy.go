@@ -0,0 +1,118 @@
+// Package clog: enabled checks and lazily evaluated parameters.
+package clog
+
+import (
+	"fmt"
+	"runtime/debug"
+)
+
+// Lazy is a log parameter whose value is computed only if the event will be
+// written somewhere. Wrap expensive arguments with it:
+//
+//	clog.Debug("SIP", "packet:\n%s", clog.Lazy(func() any { return hex.Dump(pkt) }))
+//
+// The function runs on the agent goroutine, after the event has left the
+// queue, and only if at least one sink or hook would take the event; it must
+// therefore not depend on state the caller mutates after the log call. Its
+// result is used like any other parameter (it may also be a Field). If it
+// panics, the panic is counted in Stats.LazyPanics and the parameter becomes
+// a "%!v(PANIC=Lazy: ...)" placeholder, as fmt does for a panicking String
+// method.
+type Lazy func() interface{}
+
+// Enabled reports whether an event at level for the facility iface would be
+// written anywhere by the default logger. See Logger.Enabled.
+func Enabled(level Level, iface string) bool {
+	return Default().Enabled(level, iface)
+}
+
+// Enabled reports whether an event at level for the facility iface (joined
+// with the logger's Named prefix) would be written anywhere: it passes the
+// facility level table and at least one hook or sink would take it. Use it
+// to skip building expensive arguments:
+//
+//	if clog.Enabled(clog.LevelDebug, "SIP") {
+//		clog.Debug("SIP", "state: %s", dumpCallState())
+//	}
+func (l *Logger) Enabled(level Level, iface string) bool {
+	if l == nil {
+		return false
+	}
+	facility := l.facility(iface)
+	if !l.agent.levelEnabled(facility, level) {
+		return false
+	}
+	if l.agent.hasHooks(level) {
+		return true
+	}
+	sinks := l.agent.activeSinks.Load()
+	if sinks == nil {
+		return false
+	}
+	for _, s := range *sinks {
+		if sinkEnabled(s, level, facility) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasHooks reports whether any global or per-level hook is configured for
+// level.
+func (a *agent) hasHooks(level Level) bool {
+	hooks := a.cfg.Load().Hooks
+	return len(hooks.Global) > 0 || len(hooks.PerLevel[level]) > 0
+}
+
+// wouldWrite reports, on the agent goroutine, whether any hook or sink will
+// take e.
+func (a *agent) wouldWrite(e Event) bool {
+	if a.hasHooks(e.Level) {
+		return true
+	}
+	for _, s := range a.sinks {
+		if sinkEnabled(s, e.Level, e.Iface) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveLazy replaces Lazy params with their values when the event will be
+// written, and with nil otherwise (it is then formatted for dedupe only and
+// never seen). The caller's slice is never mutated; without Lazy params the
+// original slice is returned.
+func (a *agent) resolveLazy(e Event) []interface{} {
+	for i, p := range e.Params {
+		if _, ok := p.(Lazy); !ok {
+			continue
+		}
+		write := a.wouldWrite(e)
+		out := make([]interface{}, len(e.Params))
+		copy(out, e.Params)
+		for j := i; j < len(out); j++ {
+			fn, ok := out[j].(Lazy)
+			if !ok {
+				continue
+			}
+			out[j] = nil
+			if write && fn != nil {
+				out[j] = a.callLazy(fn)
+			}
+		}
+		return out
+	}
+	return e.Params
+}
+
+// callLazy returns fn's value, or a placeholder if fn panics.
+func (a *agent) callLazy(fn Lazy) (v interface{}) {
+	defer func() {
+		if p := recover(); p != nil {
+			a.stats.lazyPanics.Add(1)
+			a.diagf("lazy", "Lazy param: panic: %v\n%s", p, debug.Stack())
+			v = fmt.Sprintf("%%!v(PANIC=Lazy: %v)", p)
+		}
+	}()
+	return fn()
+}
@@ -0,0 +1,124 @@
+package clog
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+)
+
+func TestEnabled_ConsultsSinksHooksAndFacilities(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Console.OmitLevels = map[Level]bool{LevelDebug: true}
+	cfg.File.BaseDir = t.TempDir()
+	cfg.File.PerLevel = map[Level]string{LevelError: "error.log"}
+	cfg.FacilityLevels = FacilityLevels{"RTP": LevelError}
+	l, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer l.Shutdown(context.Background())
+
+	tests := []struct {
+		level Level
+		iface string
+		want  bool
+	}{
+		{LevelDebug, "SIP", false}, // omitted by console, no file for Debug
+		{LevelInfo, "SIP", true},   // console
+		{LevelInfo, "RTP", false},  // facility minimum is Error
+		{LevelError, "RTP", true},
+	}
+	for _, tt := range tests {
+		if got := l.Enabled(tt.level, tt.iface); got != tt.want {
+			t.Errorf("Enabled(%s, %q) = %v, want %v", tt.level, tt.iface, got, tt.want)
+		}
+	}
+
+	cfg.Hooks.PerLevel = map[Level][]Hook{LevelDebug: {&testHook{}}}
+	if err := l.Reconfigure(cfg); err != nil {
+		t.Fatalf("Reconfigure: %v", err)
+	}
+	if !l.Enabled(LevelDebug, "SIP") {
+		t.Error("Enabled(Debug) = false with a Debug hook configured")
+	}
+
+	var nilLogger *Logger
+	if nilLogger.Enabled(LevelCatastrophe, "SIP") {
+		t.Error("nil logger reports enabled")
+	}
+}
+
+func TestLazy_EvaluatedOnlyWhenWritten(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Console.Enabled = false
+	cfg.Dedupe.Enabled = false
+	cfg.File.BaseDir = t.TempDir()
+	cfg.File.PerLevel = map[Level]string{LevelError: "error.log"}
+	l, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	var calls atomic.Int32
+	dump := Lazy(func() interface{} {
+		calls.Add(1)
+		return "expensive"
+	})
+	l.Debug("SIP", "dump %s", dump)
+	l.Info("SIP", "dump %s", dump)
+	l.Shutdown(context.Background())
+	if n := calls.Load(); n != 0 {
+		t.Errorf("Lazy evaluated %d times for events no sink writes", n)
+	}
+
+	hook := &testHook{}
+	cfg.Hooks.Global = []Hook{hook}
+	l, err = New(cfg)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	l.Debug("SIP", "dump %s %v", dump, Lazy(nil),
+		Lazy(func() interface{} { return String("call_id", "c-1") }))
+	l.Shutdown(context.Background())
+
+	if n := calls.Load(); n != 1 {
+		t.Errorf("Lazy evaluated %d times, want 1", n)
+	}
+	events := hook.getEvents()
+	if len(events) != 1 {
+		t.Fatalf("got %d events, want 1", len(events))
+	}
+	if events[0].Message != "dump expensive <nil>" {
+		t.Errorf("Message = %q", events[0].Message)
+	}
+	if fieldMap(events[0])["call_id"] != "c-1" {
+		t.Errorf("Lazy returning a Field not treated as a field: %v", events[0].Fields)
+	}
+}
+
+func TestLazy_PanicIsRecovered(t *testing.T) {
+	hook := &testHook{}
+	cfg := panicsConfig(false)
+	cfg.Hooks.Global = []Hook{hook}
+	l, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	l.Info("SIP", "state %v", Lazy(func() interface{} { panic("bad state") }))
+	l.Info("SIP", "still logging")
+	l.Shutdown(context.Background())
+
+	events := hook.getEvents()
+	if len(events) != 2 {
+		t.Fatalf("got %d events, want 2", len(events))
+	}
+	if want := "state %!v(PANIC=Lazy: bad state)"; events[0].Message != want {
+		t.Errorf("Message = %q, want %q", events[0].Message, want)
+	}
+	if events[1].Message != "still logging" {
+		t.Errorf("Message = %q after the panic", events[1].Message)
+	}
+	if n := l.Stats().LazyPanics; n != 1 {
+		t.Errorf("LazyPanics = %d, want 1", n)
+	}
+}
@@ -1,6 +1,11 @@
 // Package clog: log level definitions and constants.
 package clog
 
+import (
+	"fmt"
+	"strings"
+)
+
 // Level represents a log level.
 type Level int
 
@@ -42,7 +47,78 @@ func (l Level) String() string {
 	}
 }
 
+// ShortString returns the three-letter form of the level (DBG, INF, SUC,
+// WRN, FAL, ERR, CAT) for compact output. ParseLevel accepts it.
+func (l Level) ShortString() string {
+	switch l {
+	case LevelDebug:
+		return "DBG"
+	case LevelInfo:
+		return "INF"
+	case LevelSuccess:
+		return "SUC"
+	case LevelWarning:
+		return "WRN"
+	case LevelFail:
+		return "FAL"
+	case LevelError:
+		return "ERR"
+	case LevelCatastrophe:
+		return "CAT"
+	default:
+		return "UNK"
+	}
+}
 
+// ParseLevel parses a level name, case-insensitively and ignoring
+// surrounding spaces. Besides the names String returns it accepts the
+// ShortString forms and the aliases "warn", "err", "failure", "fatal",
+// "critical" and "crit".
+func ParseLevel(s string) (Level, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "debug", "dbg":
+		return LevelDebug, nil
+	case "info", "inf":
+		return LevelInfo, nil
+	case "success", "suc":
+		return LevelSuccess, nil
+	case "warning", "warn", "wrn":
+		return LevelWarning, nil
+	case "fail", "failure", "fal":
+		return LevelFail, nil
+	case "error", "err":
+		return LevelError, nil
+	case "catastrophe", "cat", "fatal", "critical", "crit":
+		return LevelCatastrophe, nil
+	default:
+		return 0, fmt.Errorf("unknown level %q", s)
+	}
+}
 
+// MarshalText implements encoding.TextMarshaler, so levels appear by name in
+// JSON (including as map keys, e.g. Stats.DropsPerLevel). It fails for a
+// value outside LevelDebug..LevelCatastrophe.
+func (l Level) MarshalText() ([]byte, error) {
+	if l < LevelDebug || l > LevelCatastrophe {
+		return nil, fmt.Errorf("invalid level %d", int(l))
+	}
+	return []byte(l.String()), nil
+}
 
+// UnmarshalText implements encoding.TextUnmarshaler using ParseLevel, so a
+// Config decoded with encoding/json takes level names for MinLevel,
+// OmitLevels, PerLevel, ShedThresholds and FacilityLevels.
+func (l *Level) UnmarshalText(text []byte) error {
+	level, err := ParseLevel(string(text))
+	if err != nil {
+		return err
+	}
+	*l = level
+	return nil
+}
 
+// Set implements flag.Value with ParseLevel, so a Level can be a
+// command-line flag: flag.Var(&level, "level", "minimum level").
+func (l *Level) Set(s string) error {
+	return l.UnmarshalText([]byte(s))
+}
@@ -1,6 +1,12 @@
 package clog
 
-import "testing"
+import (
+	"encoding/json"
+	"flag"
+	"io"
+	"strings"
+	"testing"
+)
 
 func TestLevel_String(t *testing.T) {
 	tests := []struct {
@@ -43,3 +49,79 @@ func TestLevel_AtLeast(t *testing.T) {
 		}
 	}
 }
+
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Level
+	}{
+		{"debug", LevelDebug},
+		{" INFO ", LevelInfo},
+		{"Success", LevelSuccess},
+		{"warn", LevelWarning},
+		{"WRN", LevelWarning},
+		{"failure", LevelFail},
+		{"err", LevelError},
+		{"fatal", LevelCatastrophe},
+		{"crit", LevelCatastrophe},
+	}
+	for _, tt := range tests {
+		if got, err := ParseLevel(tt.in); err != nil || got != tt.want {
+			t.Errorf("ParseLevel(%q) = %v, %v; want %v", tt.in, got, err, tt.want)
+		}
+	}
+	for l := LevelDebug; l <= LevelCatastrophe; l++ {
+		for _, s := range []string{l.String(), l.ShortString()} {
+			if got, err := ParseLevel(s); err != nil || got != l {
+				t.Errorf("ParseLevel(%q) = %v, %v; want %v", s, got, err, l)
+			}
+		}
+	}
+	if _, err := ParseLevel("trace"); err == nil || !strings.Contains(err.Error(), `unknown level "trace"`) {
+		t.Errorf("ParseLevel(trace): err = %v", err)
+	}
+}
+
+func TestLevel_TextMarshaling(t *testing.T) {
+	b, err := json.Marshal(map[Level]int64{LevelWarning: 2})
+	if err != nil || string(b) != `{"WARNING":2}` {
+		t.Errorf("Marshal = %s, %v", b, err)
+	}
+	if _, err := Level(42).MarshalText(); err == nil {
+		t.Error("MarshalText(42): want an error")
+	}
+
+	// A Config decoded with encoding/json takes level names everywhere.
+	var cfg Config
+	err = json.Unmarshal([]byte(`{
+		"Console": {"OmitLevels": {"debug": true}},
+		"File": {"PerLevel": {"warn": "w.log"}},
+		"Sinks": [{"Type": "betterstack", "MinLevel": "err"}],
+		"Caller": {"MinLevel": "fatal"},
+		"FacilityLevels": {"SIP.*": "dbg"}
+	}`), &cfg)
+	if err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if !cfg.Console.OmitLevels[LevelDebug] || cfg.File.PerLevel[LevelWarning] != "w.log" ||
+		cfg.Sinks[0].MinLevel != LevelError || cfg.Caller.MinLevel != LevelCatastrophe ||
+		cfg.FacilityLevels["SIP.*"] != LevelDebug {
+		t.Errorf("Unmarshal = %+v", cfg)
+	}
+	if err := json.Unmarshal([]byte(`{"Caller": {"MinLevel": "loud"}}`), &cfg); err == nil {
+		t.Error("unknown level name: want an error")
+	}
+}
+
+func TestLevel_Flag(t *testing.T) {
+	level := LevelInfo
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.Var(&level, "level", "minimum level")
+	if err := fs.Parse([]string{"-level", "warn"}); err != nil || level != LevelWarning {
+		t.Errorf("Parse = %v, level = %v", err, level)
+	}
+	if err := fs.Parse([]string{"-level", "loud"}); err == nil {
+		t.Error("bad level: want an error")
+	}
+}
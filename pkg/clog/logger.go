@@ -0,0 +1,231 @@
+// Package clog: Logger type for independent, non-global logger instances.
+package clog
+
+import (
+	"context"
+	"time"
+)
+
+// Logger is an independent logger with its own agent, sinks, dedupe state and
+// statistics. Libraries can create one with New without touching the host
+// application's package-level logger. The package-level functions (Info,
+// Error, ...) are thin wrappers over a default Logger created by Init.
+//
+// A nil *Logger is valid and discards everything, which is what the
+// package-level functions rely on before Init and after Shutdown.
+//
+// With and Named return lightweight child loggers that share the parent's
+// agent (queue, sinks, dedupe state and stats) but attach a bound facility
+// name and bound fields to every event.
+type Logger struct {
+	agent  *agent
+	name   string
+	fields []Field
+}
+
+// New creates and starts a Logger with the given configuration. Call
+// Shutdown on the returned Logger to drain its queue and close its sinks.
+func New(cfg Config) (*Logger, error) {
+	return newLogger(cfg, &statsState{})
+}
+
+// newLogger creates a Logger whose agent records into stats.
+func newLogger(cfg Config, stats *statsState) (*Logger, error) {
+	a, err := newAgentWithStats(cfg, stats)
+	if err != nil {
+		return nil, err
+	}
+	return &Logger{agent: a}, nil
+}
+
+// With returns a child logger that attaches fields to every event it logs,
+// ahead of any fields passed to the individual call. Use it to bind per-call
+// context (call_id, conference_id, ...) once when a call starts.
+func (l *Logger) With(fields ...Field) *Logger {
+	if l == nil {
+		return nil
+	}
+	child := *l
+	// Full slice expression: appending to the child never writes into the
+	// parent's (or a sibling's) backing array.
+	child.fields = append(l.fields[:len(l.fields):len(l.fields)], fields...)
+	return &child
+}
+
+// Named returns a child logger with a bound facility name. Names nest with
+// dots: Named("SIP").Named("Transport") logs as "SIP.Transport". A call with an
+// empty iface logs under the bound name; a non-empty iface is appended to it
+// as a sub-facility ("SIP.Transport" + "TLS" -> "SIP.Transport.TLS").
+func (l *Logger) Named(name string) *Logger {
+	if l == nil {
+		return nil
+	}
+	child := *l
+	child.name = joinFacility(l.name, name)
+	return &child
+}
+
+// joinFacility joins a bound facility name and a call-site facility with a dot,
+// skipping whichever is empty.
+func joinFacility(base, name string) string {
+	switch {
+	case base == "":
+		return name
+	case name == "":
+		return base
+	default:
+		return base + "." + name
+	}
+}
+
+// Shutdown gracefully shuts down the logger: it stops accepting events, drains
+// the queue, and flushes and closes every sink and the audio writer.
+// Subsequent log calls on l are no-ops. Child loggers from With and Named
+// share their parent's agent, so shutting down any of them shuts down all.
+func (l *Logger) Shutdown(ctx context.Context) {
+	if l == nil {
+		return
+	}
+	l.agent.stop(ctx)
+}
+
+// Stats returns current statistics for this logger.
+func (l *Logger) Stats() Stats {
+	if l == nil {
+		return (&statsState{}).snapshot()
+	}
+	return l.agent.stats.snapshot()
+}
+
+// log enqueues a log event at the specified level.
+func (l *Logger) log(level Level, iface, msg string, params []interface{}) {
+	if l == nil {
+		return
+	}
+	facility := l.facility(iface)
+	if !l.agent.levelEnabled(facility, level) {
+		return
+	}
+	l.logFields(level, facility, msg, params, l.fields)
+}
+
+// facility returns the full facility for a call-site iface: the bound name
+// joined with iface, or the default facility when both are empty.
+func (l *Logger) facility(iface string) string {
+	facility := joinFacility(l.name, iface)
+	if facility == "" {
+		return defaultIface
+	}
+	return facility
+}
+
+// logFields builds and enqueues an event carrying fields for an already
+// resolved and level-checked facility. It is the common tail of log and
+// logCtx.
+func (l *Logger) logFields(level Level, facility, msg string, params []interface{}, fields []Field) {
+	l.submit(Event{
+		Level:   level,
+		Iface:   facility,
+		Message: msg,
+		Params:  params,
+		Fields:  fields,
+		pc:      l.agent.cfg.Load().Caller.capturePC(level),
+	})
+}
+
+// submit hands an event to the agent; see agent.submit.
+func (l *Logger) submit(e Event) {
+	l.agent.submit(e)
+}
+
+// submit stamps an event, enqueues it (or, in synchronous mode, handles it
+// inline) and records the outcome in stats.
+func (a *agent) submit(e Event) {
+	e = a.stamp(e)
+	if a.synchronous {
+		if !a.handleInline(e) {
+			a.stats.recordDrop(e.Level)
+		}
+		return
+	}
+	switch a.tryEnqueue(e) {
+	case enqueued:
+		a.stats.recordAccepted()
+	case droppedTimeout:
+		a.stats.recordTimeoutDrop(e.Level)
+	case shed:
+		a.stats.recordShed(e.Level)
+	default:
+		a.stats.recordDrop(e.Level)
+	}
+}
+
+// stamp sets an event's sequence number, and the current time unless the
+// caller already set one.
+func (a *agent) stamp(e Event) Event {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	e.Seq = a.seq.Add(1)
+	return e
+}
+
+// Debug logs a debug message.
+func (l *Logger) Debug(iface, msg string, params ...interface{}) {
+	l.log(LevelDebug, iface, msg, params)
+}
+
+// Info logs an info message.
+func (l *Logger) Info(iface, msg string, params ...interface{}) {
+	l.log(LevelInfo, iface, msg, params)
+}
+
+// Success logs a success message.
+func (l *Logger) Success(iface, msg string, params ...interface{}) {
+	l.log(LevelSuccess, iface, msg, params)
+}
+
+// Warning logs a warning message.
+func (l *Logger) Warning(iface, msg string, params ...interface{}) {
+	l.log(LevelWarning, iface, msg, params)
+}
+
+// Fail logs a fail message.
+func (l *Logger) Fail(iface, msg string, params ...interface{}) {
+	l.log(LevelFail, iface, msg, params)
+}
+
+// Error logs an error message.
+func (l *Logger) Error(iface, msg string, params ...interface{}) {
+	l.log(LevelError, iface, msg, params)
+}
+
+// Catastrophe logs a catastrophe message.
+func (l *Logger) Catastrophe(iface, msg string, params ...interface{}) {
+	l.log(LevelCatastrophe, iface, msg, params)
+}
+
+// AudioWritePCM16 writes PCM16 frames to this logger's audio log.
+func (l *Logger) AudioWritePCM16(frames []int16) {
+	if l == nil {
+		return
+	}
+	l.agent.audioMu.RLock()
+	if l.agent.audioWriter != nil {
+		_ = l.agent.audioWriter.WritePCM16(frames)
+	}
+	l.agent.audioMu.RUnlock()
+}
+
+// AudioWriteBytesPCM16LE writes PCM16 little-endian bytes to this logger's
+// audio log.
+func (l *Logger) AudioWriteBytesPCM16LE(data []byte) {
+	if l == nil {
+		return
+	}
+	l.agent.audioMu.RLock()
+	if l.agent.audioWriter != nil {
+		_ = l.agent.audioWriter.WriteBytesPCM16LE(data)
+	}
+	l.agent.audioMu.RUnlock()
+}
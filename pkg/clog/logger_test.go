@@ -0,0 +1,222 @@
+package clog
+
+import (
+	"context"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// waitForEvents polls hook until it has recorded at least n events or the
+// deadline passes, and returns whatever it has.
+func waitForEvents(hook *testHook, n int) []Event {
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if events := hook.getEvents(); len(events) >= n {
+			return events
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return hook.getEvents()
+}
+
+func TestNew_IndependentOfDefault(t *testing.T) {
+	defaultHook := &testHook{}
+	cfg := DefaultConfig()
+	cfg.Console.Enabled = false
+	cfg.Hooks.Global = []Hook{defaultHook}
+	Init(cfg)
+	defer Shutdown(context.Background())
+
+	libHook := &testHook{}
+	libCfg := DefaultConfig()
+	libCfg.Console.Enabled = false
+	libCfg.Hooks.Global = []Hook{libHook}
+	lib, err := New(libCfg)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	lib.Info("Lib", "from library")
+	lib.Error("Lib", "library error")
+	Info("App", "from application")
+
+	lib.Shutdown(context.Background())
+
+	libEvents := libHook.getEvents()
+	if len(libEvents) != 2 {
+		t.Fatalf("library hook got %d events, want 2", len(libEvents))
+	}
+	for _, e := range libEvents {
+		if e.Iface != "Lib" {
+			t.Errorf("library hook received foreign event %+v", e)
+		}
+	}
+
+	appEvents := waitForEvents(defaultHook, 1)
+	if len(appEvents) != 1 || appEvents[0].Iface != "App" {
+		t.Errorf("default hook events = %+v, want only the App event", appEvents)
+	}
+
+	if !IsInitialized() {
+		t.Error("shutting down a Logger from New must not shut down the default logger")
+	}
+
+	stats := lib.Stats()
+	if stats.AcceptedCount != 2 || stats.EmittedCount != 2 {
+		t.Errorf("lib.Stats() accepted=%d emitted=%d, want 2/2", stats.AcceptedCount, stats.EmittedCount)
+	}
+}
+
+func TestLogger_AfterShutdownIsNoop(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Console.Enabled = false
+	l, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	l.Shutdown(context.Background())
+	l.Shutdown(context.Background()) // second Shutdown is a no-op
+
+	l.Info("Test", "after shutdown")
+	l.AudioWritePCM16([]int16{1, 2, 3})
+
+	stats := l.Stats()
+	if stats.AcceptedCount != 0 {
+		t.Errorf("AcceptedCount = %d after shutdown, want 0", stats.AcceptedCount)
+	}
+	if stats.DropsPerLevel[LevelInfo] != 1 {
+		t.Errorf("DropsPerLevel[Info] = %d, want 1", stats.DropsPerLevel[LevelInfo])
+	}
+}
+
+func TestLogger_AudioDefaultsTo16Bits(t *testing.T) {
+	dir := t.TempDir()
+	cfg := DefaultConfig()
+	cfg.Console.Enabled = false
+	cfg.Audio = AudioConfig{Enabled: true, SampleRate: 8000, Channels: 2, OutputDir: dir,
+		FilenamePattern: "audio.wav"}
+	l, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	l.AudioWritePCM16([]int16{1, 2, 3, 4})
+	l.Shutdown(context.Background())
+
+	b, err := os.ReadFile(filepath.Join(dir, "audio.wav"))
+	if err != nil || len(b) < 44 {
+		t.Fatalf("read WAV: %d bytes, %v", len(b), err)
+	}
+	byteRate := binary.LittleEndian.Uint32(b[28:32])
+	blockAlign := binary.LittleEndian.Uint16(b[32:34])
+	bits := binary.LittleEndian.Uint16(b[34:36])
+	if bits != 16 || blockAlign != 4 || byteRate != 32000 {
+		t.Errorf("header: bits %d, block align %d, byte rate %d; want 16, 4, 32000", bits, blockAlign, byteRate)
+	}
+}
+
+func TestLogger_NilIsNoop(t *testing.T) {
+	var l *Logger
+	l.Info("Test", "discarded")
+	l.AudioWriteBytesPCM16LE([]byte{0, 1})
+	l.Shutdown(context.Background())
+	if got := l.Stats().AcceptedCount; got != 0 {
+		t.Errorf("nil Logger AcceptedCount = %d, want 0", got)
+	}
+}
+
+func TestDefault(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Console.Enabled = false
+	Init(cfg)
+	if Default() == nil {
+		t.Fatal("Default() = nil after Init")
+	}
+	Shutdown(context.Background())
+	if Default() != nil {
+		t.Error("Default() != nil after Shutdown")
+	}
+}
+
+func TestLogger_NamedAndWith(t *testing.T) {
+	hook := &testHook{}
+	cfg := DefaultConfig()
+	cfg.Console.Enabled = false
+	cfg.Dedupe.Enabled = false
+	cfg.Hooks.Global = []Hook{hook}
+	root, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	sip := root.Named("SIP").Named("Transport")
+	call := sip.With(String("call_id", "c-1"))
+	other := sip.With(String("call_id", "c-2"))
+
+	call.Info("", "INVITE received", Int("cseq", 1))
+	call.Warning("TLS", "handshake slow")
+	other.Info("", "BYE received")
+	root.Info("", "unbound")
+
+	root.Shutdown(context.Background())
+
+	events := hook.getEvents()
+	if len(events) != 4 {
+		t.Fatalf("got %d events, want 4", len(events))
+	}
+	tests := []struct {
+		iface  string
+		fields []string
+	}{
+		{"SIP.Transport", []string{"call_id=c-1", "cseq=1"}},
+		{"SIP.Transport.TLS", []string{"call_id=c-1"}},
+		{"SIP.Transport", []string{"call_id=c-2"}},
+		{defaultIface, nil},
+	}
+	for i, tt := range tests {
+		e := events[i]
+		if e.Iface != tt.iface {
+			t.Errorf("event %d Iface = %q, want %q", i, e.Iface, tt.iface)
+		}
+		var got []string
+		for _, f := range e.Fields {
+			got = append(got, f.Key+"="+f.String())
+		}
+		if len(got) != len(tt.fields) {
+			t.Errorf("event %d fields = %v, want %v", i, got, tt.fields)
+			continue
+		}
+		for j := range got {
+			if got[j] != tt.fields[j] {
+				t.Errorf("event %d fields = %v, want %v", i, got, tt.fields)
+				break
+			}
+		}
+	}
+
+	if root.Stats().EmittedCount != 4 {
+		t.Errorf("children should share the parent's stats, emitted = %d", root.Stats().EmittedCount)
+	}
+}
+
+func TestLogger_WithDoesNotAlias(t *testing.T) {
+	base := (&Logger{}).With(String("a", "1"), String("b", "2"))
+	base.fields = base.fields[:1] // leave spare capacity behind a
+	x := base.With(String("x", "x"))
+	y := base.With(String("y", "y"))
+	if x.fields[1].Key != "x" || y.fields[1].Key != "y" {
+		t.Errorf("sibling children share backing storage: x=%v y=%v", x.fields, y.fields)
+	}
+	if len(base.fields) != 1 {
+		t.Errorf("With modified the parent: %v", base.fields)
+	}
+}
+
+func TestLogger_NilChildren(t *testing.T) {
+	var l *Logger
+	if l.With(String("k", "v")) != nil || l.Named("X") != nil {
+		t.Error("With/Named on a nil Logger should return nil")
+	}
+}
@@ -0,0 +1,5 @@
+//go:build !race
+
+package clog
+
+const raceEnabled = false
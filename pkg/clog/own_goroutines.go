@@ -0,0 +1,44 @@
+// Package clog: recognizing calls made from the logger's own goroutines.
+package clog
+
+import (
+	"bytes"
+	"runtime"
+	"strconv"
+	"sync"
+)
+
+// ownGoroutines records the goroutines that run a logger's pipeline: the
+// agent and the sink workers. A hook, OnSinkError callback or quarantine
+// notice that calls back into the logger runs on one of them, where waiting
+// for the queue to drain or for a control request to be handled would wait
+// on itself.
+type ownGoroutines struct {
+	ids sync.Map // goroutine id -> struct{}
+}
+
+// enter records the calling goroutine until the returned func is called.
+func (o *ownGoroutines) enter() (leave func()) {
+	id := goroutineID()
+	o.ids.Store(id, struct{}{})
+	return func() { o.ids.Delete(id) }
+}
+
+// current reports whether the calling goroutine was recorded by enter. It
+// reads the goroutine's stack header, so it is only for slow paths.
+func (o *ownGoroutines) current() bool {
+	_, ok := o.ids.Load(goroutineID())
+	return ok
+}
+
+// goroutineID returns the id of the calling goroutine, parsed from the
+// "goroutine N [running]:" header of its stack trace.
+func goroutineID() uint64 {
+	var buf [64]byte
+	b := bytes.TrimPrefix(buf[:runtime.Stack(buf[:], false)], []byte("goroutine "))
+	if i := bytes.IndexByte(b, ' '); i >= 0 {
+		b = b[:i]
+	}
+	id, _ := strconv.ParseUint(string(b), 10, 64)
+	return id
+}
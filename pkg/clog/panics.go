@@ -0,0 +1,255 @@
+// Package clog: panic isolation and quarantine for hooks and sinks.
+package clog
+
+import (
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"strconv"
+	"sync/atomic"
+)
+
+// defaultMaxConsecutivePanics is used when Config.MaxConsecutivePanics is 0.
+const defaultMaxConsecutivePanics = 3
+
+// errQuarantined is reported when flushing a quarantined sink.
+var errQuarantined = errors.New("sink quarantined after repeated panics")
+
+// maxConsecutivePanics returns MaxConsecutivePanics or its default.
+func (c Config) maxConsecutivePanics() int {
+	if c.MaxConsecutivePanics == 0 {
+		return defaultMaxConsecutivePanics
+	}
+	return c.MaxConsecutivePanics
+}
+
+// panicValueError is the SinkError.Err of a recovered panic.
+type panicValueError struct {
+	value interface{}
+	stack []byte // of the panicking goroutine, for the diagnostics output
+}
+
+func (e panicValueError) Error() string {
+	return fmt.Sprintf("panic: %v", e.value)
+}
+
+// quarantineEvent returns the Catastrophe event announcing that component
+// was disabled after n consecutive panics, the last one with value v.
+func quarantineEvent(component string, n int, v interface{}) Event {
+	return Event{
+		Level:   LevelCatastrophe,
+		Iface:   "System",
+		Message: "%s quarantined after %d consecutive panics, last: %v",
+		Params:  []interface{}{component, n, v},
+	}
+}
+
+// sinkSupervisor is shared by the sinks started together: it receives their
+// errors and announces a quarantine.
+type sinkSupervisor struct {
+	report    func(*SinkError) // nil: errors are only counted
+	maxPanics int
+	notify    func(Event)    // logs a quarantine event; nil: none
+	own       *ownGoroutines // the workers are recorded here; nil: not at all
+}
+
+// enterWorker records the calling sink worker in s.own until the returned
+// func is called. s may be nil.
+func (s *sinkSupervisor) enterWorker() (leave func()) {
+	if s == nil || s.own == nil {
+		return func() {}
+	}
+	return s.own.enter()
+}
+
+// sinkGuard is one started sink's error and panic bookkeeping. Every call
+// into the sink goes through call (or enabled). After maxPanics panics in a row
+// the sink is quarantined: its wrapper drops further events and stops
+// calling it except to close it.
+type sinkGuard struct {
+	name        string
+	stats       *sinkCounters
+	sup         *sinkSupervisor // may be nil
+	consecutive atomic.Int32
+	quarantined atomic.Bool
+}
+
+// newSinkGuard starts the bookkeeping for a sink, clearing a quarantine left
+// in stats by an earlier sink of the same name.
+func newSinkGuard(name string, stats *sinkCounters, sup *sinkSupervisor) sinkGuard {
+	stats.quarantined.Store(false)
+	return sinkGuard{name: name, stats: stats, sup: sup}
+}
+
+// fail counts and reports err (if not nil) as a failed op of the sink.
+func (g *sinkGuard) fail(op string, err error) {
+	var report func(*SinkError)
+	if g.sup != nil {
+		report = g.sup.report
+	}
+	recordSinkError(g.name, op, err, g.stats, report)
+}
+
+// call runs f, a call into the sink for op, and counts and reports its
+// error. A panic is recovered and returned as the error (see settle); a call
+// that returns ends a run of panics.
+func (g *sinkGuard) call(op string, f func() error) (err error) {
+	defer g.settle(op, &err)
+	err = f()
+	g.consecutive.Store(0)
+	g.fail(op, err)
+	return err
+}
+
+// enabled asks inner whether it takes events at level from iface. A
+// quarantined sink takes none. A panic is counted and taken as yes, leaving
+// quarantine to the write.
+func (g *sinkGuard) enabled(inner Sink, level Level, iface string) (ok bool) {
+	if g.quarantined.Load() {
+		return false
+	}
+	defer func() {
+		if v := recover(); v != nil {
+			g.stats.panics.Add(1)
+			g.fail("enabled", panicValueError{v, debug.Stack()})
+			ok = true
+		}
+	}()
+	return sinkEnabled(inner, level, iface)
+}
+
+// settle is deferred by call. It recovers a panic, reports it as an error
+// of op (stored in *errp) and quarantines the sink after too many in a row.
+func (g *sinkGuard) settle(op string, errp *error) {
+	v := recover()
+	if v == nil {
+		return
+	}
+	err := panicValueError{v, debug.Stack()}
+	*errp = err
+	g.stats.panics.Add(1)
+	g.fail(op, err)
+	n := int(g.consecutive.Add(1))
+	max := defaultMaxConsecutivePanics
+	if g.sup != nil {
+		max = g.sup.maxPanics
+	}
+	if n < max || g.quarantined.Swap(true) {
+		return
+	}
+	g.stats.quarantined.Store(true)
+	if g.sup != nil && g.sup.notify != nil {
+		g.sup.notify(quarantineEvent("sink "+strconv.Quote(g.name), n, v))
+	}
+}
+
+// onSinkErrorName is the Stats.Hooks key of Config.OnSinkError.
+const onSinkErrorName = "OnSinkError"
+
+// callOnSinkError hands err to the OnSinkError callback, recovering a panic
+// like a hook's: it is counted in Stats.Hooks["OnSinkError"] and reported on
+// the diagnostics output, but the callback is not quarantined. It also runs
+// from sinkGuard.settle, after that has recovered the sink's own panic, so
+// without this a panicking callback would crash the sink worker or agent.
+func (a *agent) callOnSinkError(onError func(*SinkError), err *SinkError) {
+	defer func() {
+		if v := recover(); v != nil {
+			a.stats.hook(onSinkErrorName).panics.Add(1)
+			a.diagf(onSinkErrorName, "OnSinkError: panic: %v\n%s", v, debug.Stack())
+		}
+	}()
+	onError(err)
+}
+
+// guardedHook is one configured hook with its panic bookkeeping, used only
+// on the agent goroutine (or under syncMu).
+type guardedHook struct {
+	name        string // "Hooks.Global[0]", "Hooks.PerLevel[ERROR][1]"
+	hook        Hook
+	stats       *hookCounters
+	consecutive int
+	quarantined bool
+}
+
+// hookSet is Config.Hooks with panic bookkeeping.
+type hookSet struct {
+	global   []*guardedHook
+	perLevel map[Level][]*guardedHook
+}
+
+// newHookSet wraps the hooks of cfg, with counters from stats.
+func newHookSet(cfg HooksConfig, stats *statsState) *hookSet {
+	guard := func(name string, h Hook) *guardedHook {
+		c := stats.hook(name)
+		c.quarantined.Store(false)
+		return &guardedHook{name: name, hook: h, stats: c}
+	}
+	hs := &hookSet{}
+	for i, h := range cfg.Global {
+		hs.global = append(hs.global, guard(fmt.Sprintf("Hooks.Global[%d]", i), h))
+	}
+	for level, hooks := range cfg.PerLevel {
+		if hs.perLevel == nil {
+			hs.perLevel = make(map[Level][]*guardedHook)
+		}
+		for i, h := range hooks {
+			hs.perLevel[level] = append(hs.perLevel[level], guard(fmt.Sprintf("Hooks.PerLevel[%s][%d]", level, i), h))
+		}
+	}
+	return hs
+}
+
+// callHook hands e to h unless it is quarantined, recovering a panic.
+func (a *agent) callHook(h *guardedHook, e Event) {
+	if h.quarantined {
+		return
+	}
+	defer a.settleHook(h)
+	h.hook.OnLog(e)
+}
+
+// settleHook is deferred around a hook call; see sinkGuard.settle. The
+// quarantine event is queued as a notice, since hooks run inside the
+// pipeline.
+func (a *agent) settleHook(h *guardedHook) {
+	v := recover()
+	if v == nil {
+		h.consecutive = 0
+		return
+	}
+	h.stats.panics.Add(1)
+	h.consecutive++
+	a.diagf(h.name, "hook %s (%T): panic: %v\n%s", h.name, h.hook, v, debug.Stack())
+	if h.consecutive < a.cfg.Load().maxConsecutivePanics() {
+		return
+	}
+	h.quarantined = true
+	h.stats.quarantined.Store(true)
+	a.addNotice(quarantineEvent(fmt.Sprintf("hook %s (%T)", h.name, h.hook), h.consecutive, v))
+}
+
+// addNotice queues an event the logger reports about itself from inside the
+// pipeline, where logging it directly could deadlock; it is processed right
+// after the current event.
+func (a *agent) addNotice(e Event) {
+	a.noticeMu.Lock()
+	a.notices = append(a.notices, a.stamp(e))
+	a.hasNotices.Store(true)
+	a.noticeMu.Unlock()
+}
+
+// processNotices processes the events queued by addNotice, including any
+// they queue in turn.
+func (a *agent) processNotices() {
+	for a.hasNotices.Load() {
+		a.noticeMu.Lock()
+		notices := a.notices
+		a.notices = nil
+		a.hasNotices.Store(false)
+		a.noticeMu.Unlock()
+		for _, e := range notices {
+			a.stats.recordAccepted()
+			a.processEvent(e)
+		}
+	}
+}
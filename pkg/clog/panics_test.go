@@ -0,0 +1,192 @@
+package clog
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// panickySink panics on messages containing "boom" and records the rest.
+type panickySink struct {
+	captureSink
+}
+
+func (s *panickySink) Write(level Level, iface, formatted string) {
+	if strings.Contains(formatted, "boom") {
+		panic("exploded on " + formatted)
+	}
+	s.captureSink.Write(level, iface, formatted)
+}
+
+// panicHook panics on every event.
+type panicHook struct{}
+
+func (panicHook) OnLog(e Event) { panic("hook exploded") }
+
+// recordHook keeps the formatted messages it sees.
+type recordHook struct {
+	mu   sync.Mutex
+	msgs []string
+}
+
+func (h *recordHook) OnLog(e Event) {
+	h.mu.Lock()
+	h.msgs = append(h.msgs, e.Message)
+	h.mu.Unlock()
+}
+
+func (h *recordHook) snapshot() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]string(nil), h.msgs...)
+}
+
+// panicsConfig returns a config without console, file or dedupe that
+// quarantines after two panics in a row.
+func panicsConfig(synchronous bool) Config {
+	cfg := DefaultConfig()
+	cfg.Console.Enabled = false
+	cfg.Dedupe.Enabled = false
+	cfg.Synchronous = synchronous
+	if synchronous {
+		cfg.QueueSize = 0
+	}
+	cfg.MaxConsecutivePanics = 2
+	cfg.DiagnosticsOutput = io.Discard
+	return cfg
+}
+
+func TestSinkPanics_QuarantineAfterConsecutivePanics(t *testing.T) {
+	for _, synchronous := range []bool{false, true} {
+		t.Run(fmt.Sprintf("synchronous=%v", synchronous), func(t *testing.T) {
+			bad, good := &panickySink{}, &captureSink{}
+			cfg := panicsConfig(synchronous)
+			cfg.Sinks = []SinkConfig{{Name: "bad", Instance: bad}, {Name: "good", Instance: good}}
+			l, err := New(cfg)
+			if err != nil {
+				t.Fatalf("New: %v", err)
+			}
+			defer l.Shutdown(context.Background())
+
+			// The "ok" in between ends the first run of panics.
+			for _, msg := range []string{"boom 1", "ok", "boom 2", "boom 3", "boom 4", "after"} {
+				l.Info("T", "%s", msg)
+			}
+			// The quarantine event is logged by the sink's worker; the second
+			// flush is queued behind it.
+			_ = l.Flush(context.Background())
+			_ = l.Flush(context.Background())
+
+			st := l.Stats().Sinks["bad"]
+			if st.Panics != 3 || !st.Quarantined {
+				t.Errorf("bad: Panics = %d, Quarantined = %v, want 3 and true", st.Panics, st.Quarantined)
+			}
+			if st.Errors < 3 || !strings.Contains(st.LastError, "sink bad: write: panic: exploded on boom") {
+				t.Errorf("bad: Errors = %d, LastError = %q", st.Errors, st.LastError)
+			}
+			if got := bad.snapshot(); len(got) != 1 || got[0] != "ok" {
+				t.Errorf("bad sink wrote %q, want only ok", got)
+			}
+
+			msgs := good.snapshot()
+			want := `sink "bad" quarantined after 2 consecutive panics, last: exploded on boom 3`
+			var found bool
+			for _, m := range msgs {
+				found = found || m == want
+			}
+			if !found || len(msgs) != 7 {
+				t.Errorf("good sink got %q, want every message and %q", msgs, want)
+			}
+			if got := l.Stats().Sinks["good"]; got.Panics != 0 || got.Quarantined {
+				t.Errorf("good: %+v", got)
+			}
+		})
+	}
+}
+
+func TestHookPanics_QuarantineAndReconfigure(t *testing.T) {
+	for _, synchronous := range []bool{false, true} {
+		t.Run(fmt.Sprintf("synchronous=%v", synchronous), func(t *testing.T) {
+			rec := &recordHook{}
+			sink := &captureSink{}
+			cfg := panicsConfig(synchronous)
+			cfg.Hooks.Global = []Hook{panicHook{}, rec}
+			cfg.Sinks = []SinkConfig{{Name: "capture", Instance: sink}}
+			l, err := New(cfg)
+			if err != nil {
+				t.Fatalf("New: %v", err)
+			}
+			defer l.Shutdown(context.Background())
+
+			for _, msg := range []string{"a", "b", "c"} {
+				l.Info("T", "%s", msg)
+			}
+			_ = l.Flush(context.Background())
+
+			const name = "Hooks.Global[0]"
+			if st := l.Stats().Hooks[name]; st.Panics != 2 || !st.Quarantined {
+				t.Errorf("%s: %+v, want 2 panics and quarantined", name, st)
+			}
+			want := "hook Hooks.Global[0] (clog.panicHook) quarantined after 2 consecutive panics, last: hook exploded"
+			got := rec.snapshot()
+			if len(got) != 4 || got[0] != "a" || got[1] != "b" || got[2] != want || got[3] != "c" {
+				t.Errorf("hook after the panicking one got %q", got)
+			}
+			if msgs := sink.snapshot(); len(msgs) != 4 || msgs[2] != want {
+				t.Errorf("sink got %q", msgs)
+			}
+
+			if err := l.Reconfigure(cfg); err != nil {
+				t.Fatalf("Reconfigure: %v", err)
+			}
+			if st := l.Stats().Hooks[name]; st.Quarantined {
+				t.Errorf("%s still quarantined after Reconfigure", name)
+			}
+			l.Info("T", "d")
+			_ = l.Flush(context.Background())
+			if st := l.Stats().Hooks[name]; st.Panics != 3 {
+				t.Errorf("%s: Panics = %d after Reconfigure, want 3", name, st.Panics)
+			}
+		})
+	}
+}
+
+func TestConfigValidate_NegativeMaxConsecutivePanics(t *testing.T) {
+	cfg := panicsConfig(true)
+	cfg.MaxConsecutivePanics = -1
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "MaxConsecutivePanics") {
+		t.Errorf("Validate = %v, want a MaxConsecutivePanics error", err)
+	}
+}
+
+func TestSinkPanics_PanickingOnSinkErrorIsRecovered(t *testing.T) {
+	for _, synchronous := range []bool{false, true} {
+		t.Run(fmt.Sprintf("synchronous=%v", synchronous), func(t *testing.T) {
+			bad := &panickySink{}
+			cfg := panicsConfig(synchronous)
+			cfg.MaxConsecutivePanics = 10
+			cfg.Sinks = []SinkConfig{{Name: "bad", Instance: bad}}
+			cfg.OnSinkError = func(err *SinkError) { panic("callback exploded on " + err.Op) }
+			l, err := New(cfg)
+			if err != nil {
+				t.Fatalf("New: %v", err)
+			}
+			defer l.Shutdown(context.Background())
+
+			for _, msg := range []string{"boom 1", "ok", "boom 2", "after"} {
+				l.Info("T", "%s", msg)
+			}
+			_ = l.Flush(context.Background())
+
+			if got := bad.snapshot(); len(got) != 2 || got[0] != "ok" || got[1] != "after" {
+				t.Errorf("bad sink wrote %q, want ok and after", got)
+			}
+			if st := l.Stats().Hooks["OnSinkError"]; st.Panics != 2 {
+				t.Errorf(`Stats().Hooks["OnSinkError"] = %+v, want 2 panics`, st)
+			}
+		})
+	}
+}
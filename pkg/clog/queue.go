@@ -0,0 +1,326 @@
+// Package clog: bounded lock-free queue between the loggers and the agent.
+package clog
+
+import (
+	"runtime"
+	"sync/atomic"
+	"time"
+)
+
+// dequeueBatch is the most events the agent takes off the queue at once.
+const dequeueBatch = 64
+
+// queueClosed is set in eventQueue.state once the agent stops accepting events.
+const queueClosed = 1 << 62
+
+// ring is a bounded array queue (Dmitry Vyukov's design). Each slot's seq
+// says whose turn it is: pos while the slot is free for the push at pos,
+// pos+1 once that event is published, pos+len(slots) once it was taken.
+// Producers claim the tail and consumers claim the head with a CAS, so slot
+// contents are only touched by the goroutine that claimed them. Besides the
+// agent, producers take from the head to evict the oldest event (drop_old).
+type ring struct {
+	head  atomic.Uint64
+	_     [56]byte
+	tail  atomic.Uint64
+	_     [56]byte
+	slots []ringSlot
+}
+
+type ringSlot struct {
+	seq atomic.Uint64
+	e   Event
+}
+
+func (r *ring) init(size int) {
+	r.slots = make([]ringSlot, size)
+	for i := range r.slots {
+		r.slots[i].seq.Store(uint64(i))
+	}
+}
+
+// push appends e. It reports false when the ring is full.
+func (r *ring) push(e Event) bool {
+	size := uint64(len(r.slots))
+	for {
+		pos := r.tail.Load()
+		s := &r.slots[pos%size]
+		seq := s.seq.Load()
+		switch {
+		case seq == pos:
+			if r.tail.CompareAndSwap(pos, pos+1) {
+				s.e = e
+				s.seq.Store(pos + 1)
+				return true
+			}
+		case seq < pos:
+			// The slot still holds the event pushed one lap ago. The ring
+			// is full unless that event was already claimed; then its
+			// slot is about to be released.
+			if r.head.Load()+size <= pos {
+				return false
+			}
+			runtime.Gosched()
+		}
+		// Another producer claimed pos; try the new tail.
+	}
+}
+
+// pop takes the oldest published event. ok is false when there is none,
+// which includes an event whose push has claimed the head but not yet
+// published it; pending tells the two apart.
+func (r *ring) pop() (e Event, ok bool) {
+	size := uint64(len(r.slots))
+	for {
+		pos := r.head.Load()
+		s := &r.slots[pos%size]
+		if s.seq.Load() != pos+1 {
+			if r.head.Load() == pos {
+				return Event{}, false
+			}
+			continue
+		}
+		if r.head.CompareAndSwap(pos, pos+1) {
+			e = s.e
+			s.e = Event{}
+			s.seq.Store(pos + size)
+			return e, true
+		}
+	}
+}
+
+// pending reports whether the ring holds events, published or not.
+func (r *ring) pending() bool {
+	return r.tail.Load() > r.head.Load()
+}
+
+// offerResult is the outcome of eventQueue.offer.
+type offerResult int
+
+const (
+	offerOK offerResult = iota
+	offerFull
+	offerClosed
+)
+
+// eventQueue is the agent's bounded multi-producer, single-consumer FIFO
+// queue of log events and control requests.
+//
+// n counts everything queued and is kept within limit
+// (QueueSize+PriorityReserve), the ring's size. It is raised before a push
+// and lowered only after the slot was freed, so a push admitted under limit
+// always finds room. With a PriorityReserve, low counts the queued events
+// below Error and is kept within QueueSize, leaving the rest for Error and
+// Catastrophe.
+type eventQueue struct {
+	// state is queueClosed once the agent stops, plus the number of
+	// producers inside offer.
+	state atomic.Int64
+	_     [56]byte
+	n     atomic.Int64
+	low   atomic.Int64
+	_     [48]byte
+
+	limit    int64
+	lowLimit int64 // 0 without a reserve
+	ring     ring
+	done     <-chan struct{} // the agent's done channel
+
+	// sleeping is set while the agent waits on wake for a push.
+	sleeping atomic.Bool
+	wake     chan struct{}
+
+	// waiters counts producers blocked in await; space is closed and
+	// replaced whenever the agent frees room while any are waiting.
+	waiters atomic.Int64
+	space   atomic.Pointer[chan struct{}]
+}
+
+// newEventQueue returns a queue for size events below Error plus reserve more
+// for Error and Catastrophe. done is closed when the agent stops.
+func newEventQueue(size, reserve int, done <-chan struct{}) *eventQueue {
+	q := &eventQueue{
+		limit: int64(size + reserve),
+		done:  done,
+		wake:  make(chan struct{}, 1),
+	}
+	if reserve > 0 {
+		q.lowLimit = int64(size)
+	}
+	q.ring.init(size + reserve)
+	space := make(chan struct{})
+	q.space.Store(&space)
+	return q
+}
+
+// isLow reports whether e counts against QueueSize rather than the reserve.
+func (q *eventQueue) isLow(e Event) bool {
+	return q.lowLimit > 0 && e.ctl == nil && e.Level < LevelError
+}
+
+// len returns the number of queued events and control requests.
+func (q *eventQueue) len() int {
+	return int(q.n.Load())
+}
+
+// offer queues e without waiting.
+func (q *eventQueue) offer(e Event) offerResult {
+	if q.state.Add(1)&queueClosed != 0 {
+		q.state.Add(-1)
+		return offerClosed
+	}
+	r := q.admit(e)
+	q.state.Add(-1)
+	return r
+}
+
+func (q *eventQueue) admit(e Event) offerResult {
+	low := q.isLow(e)
+	if low && !reserveCount(&q.low, q.lowLimit) {
+		return offerFull
+	}
+	if !reserveCount(&q.n, q.limit) || !q.ring.push(e) {
+		if low {
+			q.low.Add(-1)
+		}
+		return offerFull
+	}
+	q.notify()
+	return offerOK
+}
+
+// reserveCount raises c by one unless it has reached limit.
+func reserveCount(c *atomic.Int64, limit int64) bool {
+	for {
+		n := c.Load()
+		if n >= limit {
+			return false
+		}
+		if c.CompareAndSwap(n, n+1) {
+			return true
+		}
+	}
+}
+
+// notify wakes the agent if it is parked in wait.
+func (q *eventQueue) notify() {
+	if q.sleeping.Load() && q.sleeping.CompareAndSwap(true, false) {
+		select {
+		case q.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// await offers e until it is queued, waiting for the agent to free room
+// between attempts. It returns offerFull when timeout fires or cancel is
+// closed first (nil channels never do), and offerClosed once the agent stops.
+func (q *eventQueue) await(e Event, timeout <-chan time.Time, cancel <-chan struct{}) offerResult {
+	q.waiters.Add(1)
+	defer q.waiters.Add(-1)
+	for {
+		space := *q.space.Load()
+		if r := q.offer(e); r != offerFull {
+			return r
+		}
+		select {
+		case <-space:
+		case <-timeout:
+			return offerFull
+		case <-cancel:
+			return offerFull
+		case <-q.done:
+			return offerClosed
+		}
+	}
+}
+
+// evict takes the oldest queued event e may displace under drop_old. Control
+// requests are never evicted, and neither are Error and Catastrophe events
+// when e is below Error, with or without a reserve; those are moved from the
+// head to the tail instead, as the channel queue this replaced did. ok is
+// false when there is nothing to evict.
+func (q *eventQueue) evict(e Event) (old Event, ok bool) {
+	low := e.ctl == nil && e.Level < LevelError
+	// Look at each queued event at most once.
+	for looked := int64(0); looked < q.limit; {
+		if old, ok = q.ring.pop(); !ok {
+			if !q.ring.pending() {
+				return Event{}, false
+			}
+			// The oldest event is still being pushed; it is evictable
+			// once published.
+			runtime.Gosched()
+			continue
+		}
+		looked++
+		if old.ctl == nil && !(low && old.Level >= LevelError) {
+			q.release(old)
+			return old, true
+		}
+		// Its place is still counted in n, so the push finds room.
+		q.ring.push(old)
+		q.notify()
+	}
+	return Event{}, false
+}
+
+// release lowers the counts for an event taken off the ring.
+func (q *eventQueue) release(e Event) {
+	if q.isLow(e) {
+		q.low.Add(-1)
+	}
+	q.n.Add(-1)
+}
+
+// popBatch appends up to cap(buf)-len(buf) events to buf in queue order and
+// wakes producers blocked in await. Only the agent calls it.
+func (q *eventQueue) popBatch(buf []Event) []Event {
+	start := len(buf)
+	var low int64
+	for len(buf) < cap(buf) {
+		e, ok := q.ring.pop()
+		if !ok {
+			break
+		}
+		if q.isLow(e) {
+			low++
+		}
+		buf = append(buf, e)
+	}
+	taken := int64(len(buf) - start)
+	if taken == 0 {
+		return buf
+	}
+	if low > 0 {
+		q.low.Add(-low)
+	}
+	q.n.Add(-taken)
+	if q.waiters.Load() > 0 {
+		space := make(chan struct{})
+		close(*q.space.Swap(&space))
+	}
+	return buf
+}
+
+// wait parks the agent until a push or until done is closed.
+func (q *eventQueue) wait() {
+	q.sleeping.Store(true)
+	if !q.ring.pending() {
+		select {
+		case <-q.wake:
+		case <-q.done:
+		}
+	}
+	q.sleeping.Store(false)
+}
+
+// close makes every later offer fail with offerClosed, then waits for the
+// producers already inside offer to finish, so that everything accepted is
+// in the ring when it returns.
+func (q *eventQueue) close() {
+	q.state.Or(queueClosed)
+	for q.state.Load() != queueClosed {
+		runtime.Gosched()
+	}
+}
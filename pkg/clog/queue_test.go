@@ -0,0 +1,347 @@
+package clog
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// popAll drains q.
+func popAll(q *eventQueue) []Event {
+	var out []Event
+	for {
+		batch := q.popBatch(make([]Event, 0, dequeueBatch))
+		if len(batch) == 0 {
+			return out
+		}
+		out = append(out, batch...)
+	}
+}
+
+func TestEventQueue_FIFOAcrossLevels(t *testing.T) {
+	q := newEventQueue(4, 2, make(chan struct{}))
+	for _, e := range []Event{
+		{Level: LevelInfo, Seq: 1},
+		{Level: LevelError, Seq: 2},
+		{Level: LevelCatastrophe, ctl: &control{}},
+		{Level: LevelDebug, Seq: 3},
+		{Level: LevelError, Seq: 4},
+	} {
+		if r := q.offer(e); r != offerOK {
+			t.Fatalf("offer(%v) = %v", e, r)
+		}
+	}
+	var got []string
+	for _, e := range popAll(q) {
+		if e.ctl != nil {
+			got = append(got, "ctl")
+			continue
+		}
+		got = append(got, strconv.FormatUint(e.Seq, 10))
+	}
+	if want := "[1 2 ctl 3 4]"; fmt.Sprint(got) != want {
+		t.Errorf("popped %v, want %s", got, want)
+	}
+	if q.len() != 0 {
+		t.Errorf("len = %d after draining", q.len())
+	}
+}
+
+func TestEventQueue_ReserveAndEviction(t *testing.T) {
+	q := newEventQueue(2, 2, make(chan struct{}))
+	offer := func(level Level, seq uint64) offerResult {
+		return q.offer(Event{Level: level, Seq: seq})
+	}
+	offer(LevelError, 1)
+	offer(LevelDebug, 2)
+	offer(LevelDebug, 3)
+	if r := offer(LevelInfo, 4); r != offerFull {
+		t.Errorf("third below-Error event: %v, want offerFull", r)
+	}
+	if r := offer(LevelError, 5); r != offerOK {
+		t.Errorf("error into the reserve: %v, want offerOK", r)
+	}
+	if r := offer(LevelError, 6); r != offerFull {
+		t.Errorf("error past the limit: %v, want offerFull", r)
+	}
+
+	// Below-Error events evict the oldest below-Error event, moving the
+	// errors ahead of it to the tail; errors evict the oldest event.
+	low := Event{Level: LevelInfo}
+	if old, ok := q.evict(low); !ok || old.Seq != 2 {
+		t.Errorf("evict(Info) = %d, %v; want Seq 2", old.Seq, ok)
+	}
+	if old, ok := q.evict(low); !ok || old.Seq != 3 {
+		t.Errorf("evict(Info) = %d, %v; want Seq 3", old.Seq, ok)
+	}
+	if _, ok := q.evict(low); ok {
+		t.Error("evict(Info) took an error from the reserve")
+	}
+	if old, ok := q.evict(Event{Level: LevelError}); !ok || old.Seq != 5 {
+		t.Errorf("evict(Error) = %d, %v; want Seq 5", old.Seq, ok)
+	}
+	if q.len() != 1 || q.low.Load() != 0 {
+		t.Errorf("len = %d, low = %d; want 1 and 0", q.len(), q.low.Load())
+	}
+	q.evict(Event{Level: LevelError})
+	q.offer(Event{Level: LevelCatastrophe, ctl: &control{}})
+	if _, ok := q.evict(Event{Level: LevelError}); ok {
+		t.Error("evict(Error) took a control request")
+	}
+	if got := popAll(q); len(got) != 1 || got[0].ctl == nil {
+		t.Errorf("queue holds %v, want the control request", got)
+	}
+}
+
+func TestEventQueue_EvictionKeepsErrorsWithoutReserve(t *testing.T) {
+	q := newEventQueue(2, 0, make(chan struct{}))
+	q.offer(Event{Level: LevelCatastrophe, Seq: 1})
+	q.offer(Event{Level: LevelDebug, Seq: 2})
+	if old, ok := q.evict(Event{Level: LevelDebug}); !ok || old.Seq != 2 {
+		t.Errorf("evict(Debug) = %s %d, %v; want the Debug event", old.Level, old.Seq, ok)
+	}
+	q.offer(Event{Level: LevelError, Seq: 3})
+	if _, ok := q.evict(Event{Level: LevelDebug}); ok {
+		t.Error("evict(Debug) took an Error or Catastrophe event")
+	}
+	if got := popAll(q); len(got) != 2 || got[0].Seq != 1 || got[1].Seq != 3 {
+		t.Errorf("queue holds %v, want both errors", got)
+	}
+}
+
+func TestEventQueue_ConcurrentProducers(t *testing.T) {
+	const producers, perProducer = 8, 2000
+	done := make(chan struct{})
+	q := newEventQueue(16, 4, done)
+	var seq atomic.Uint64
+
+	var wg sync.WaitGroup
+	for p := 0; p < producers; p++ {
+		wg.Add(1)
+		go func(p int) {
+			defer wg.Done()
+			for i := 0; i < perProducer; i++ {
+				level := LevelInfo
+				if i%5 == 0 {
+					level = LevelError
+				}
+				s := seq.Add(1)
+				e := Event{Level: level, Iface: strconv.Itoa(p), Message: strconv.Itoa(i), Seq: s}
+				if r := q.await(e, nil, nil); r != offerOK {
+					t.Errorf("await = %v", r)
+					return
+				}
+			}
+		}(p)
+	}
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	next := make([]int, producers)
+	total := 0
+	batch := make([]Event, 0, dequeueBatch)
+	for {
+		batch = q.popBatch(batch[:0])
+		for _, e := range batch {
+			p, _ := strconv.Atoi(e.Iface)
+			i, _ := strconv.Atoi(e.Message)
+			if i != next[p] {
+				t.Fatalf("producer %d: got event %d, want %d", p, i, next[p])
+			}
+			next[p]++
+			total++
+		}
+		if len(batch) > 0 {
+			continue
+		}
+		select {
+		case <-done:
+			if batch = popAll(q); len(batch) != 0 || total != producers*perProducer {
+				t.Fatalf("received %d events (+%d late), want %d", total, len(batch), producers*perProducer)
+			}
+			return
+		default:
+		}
+		q.wait()
+	}
+}
+
+func TestEventQueue_Close(t *testing.T) {
+	done := make(chan struct{})
+	q := newEventQueue(1, 0, done)
+	q.offer(Event{Seq: 1})
+
+	result := make(chan offerResult)
+	go func() { result <- q.await(Event{Seq: 2}, nil, nil) }()
+	select {
+	case r := <-result:
+		t.Fatalf("await returned %v on a full queue", r)
+	case <-time.After(20 * time.Millisecond):
+	}
+	q.close()
+	close(done)
+	if r := <-result; r != offerClosed {
+		t.Errorf("await after close = %v, want offerClosed", r)
+	}
+	if r := q.offer(Event{Seq: 3}); r != offerClosed {
+		t.Errorf("offer after close = %v, want offerClosed", r)
+	}
+	if got := popAll(q); len(got) != 1 || got[0].Seq != 1 {
+		t.Errorf("drained %v, want the event queued before close", got)
+	}
+}
+
+// chanQueue is the enqueue path eventQueue replaced: a mutex-guarded
+// shutdown flag and a buffered channel, with drop_old's receive-then-send.
+// It is kept as the baseline for the benchmarks.
+type chanQueue struct {
+	mu       sync.Mutex
+	shutdown bool
+	ch       chan Event
+}
+
+func (q *chanQueue) offer(e Event) bool {
+	q.mu.Lock()
+	shutdown := q.shutdown
+	q.mu.Unlock()
+	if shutdown {
+		return false
+	}
+	select {
+	case q.ch <- e:
+		return true
+	default:
+		return false
+	}
+}
+
+func (q *chanQueue) offerDropOld(e Event) bool {
+	if q.offer(e) {
+		return true
+	}
+	select {
+	case <-q.ch:
+	default:
+	}
+	select {
+	case q.ch <- e:
+		return true
+	default:
+		return false
+	}
+}
+
+// benchEvent is a representative queued event.
+var benchEvent = Event{Level: LevelInfo, Iface: "SIP", Message: "call %s state %s", Params: []interface{}{"abc", "ringing"}}
+
+// BenchmarkEnqueue measures log calls from parallel goroutines into a queue
+// the agent keeps draining.
+func BenchmarkEnqueue(b *testing.B) {
+	b.Run("ring", func(b *testing.B) {
+		done := make(chan struct{})
+		q := newEventQueue(1000, 100, done)
+		var seq atomic.Uint64
+		exited := make(chan struct{})
+		go func() {
+			defer close(exited)
+			batch := make([]Event, 0, dequeueBatch)
+			for {
+				batch = q.popBatch(batch[:0])
+				clear(batch)
+				if len(batch) > 0 {
+					continue
+				}
+				select {
+				case <-done:
+					return
+				default:
+				}
+				q.wait()
+			}
+		}()
+		b.ReportAllocs()
+		b.RunParallel(func(pb *testing.PB) {
+			e := benchEvent
+			for pb.Next() {
+				e.Seq = seq.Add(1)
+				q.offer(e)
+			}
+		})
+		b.StopTimer()
+		q.close()
+		close(done)
+		<-exited
+	})
+	b.Run("channel", func(b *testing.B) {
+		q := &chanQueue{ch: make(chan Event, 1100)}
+		done := make(chan struct{})
+		exited := make(chan struct{})
+		go func() {
+			defer close(exited)
+			for {
+				select {
+				case <-q.ch:
+				case <-done:
+					return
+				}
+			}
+		}()
+		var seq atomic.Uint64
+		b.ReportAllocs()
+		b.RunParallel(func(pb *testing.PB) {
+			e := benchEvent
+			for pb.Next() {
+				e.Seq = seq.Add(1)
+				q.offer(e)
+			}
+		})
+		b.StopTimer()
+		close(done)
+		<-exited
+	})
+}
+
+// BenchmarkEnqueueDropOld measures drop_old on a full queue nobody drains,
+// where every call evicts the oldest event.
+func BenchmarkEnqueueDropOld(b *testing.B) {
+	b.Run("ring", func(b *testing.B) {
+		q := newEventQueue(1000, 0, make(chan struct{}))
+		var seq atomic.Uint64
+		var lost atomic.Int64
+		b.ReportAllocs()
+		b.RunParallel(func(pb *testing.PB) {
+			e := benchEvent
+			for pb.Next() {
+				e.Seq = seq.Add(1)
+				for q.offer(e) != offerOK {
+					if _, ok := q.evict(e); !ok {
+						lost.Add(1)
+						break
+					}
+				}
+			}
+		})
+		b.ReportMetric(float64(lost.Load())/float64(b.N), "lost/op")
+	})
+	b.Run("channel", func(b *testing.B) {
+		q := &chanQueue{ch: make(chan Event, 1000)}
+		var seq atomic.Uint64
+		var lost atomic.Int64
+		b.ReportAllocs()
+		b.RunParallel(func(pb *testing.PB) {
+			e := benchEvent
+			for pb.Next() {
+				e.Seq = seq.Add(1)
+				if !q.offerDropOld(e) {
+					lost.Add(1)
+				}
+			}
+		})
+		b.ReportMetric(float64(lost.Load())/float64(b.N), "lost/op")
+	})
+}
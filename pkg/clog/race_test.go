@@ -0,0 +1,6 @@
+//go:build race
+
+package clog
+
+// raceEnabled is set when the race detector, which allocates, is on.
+const raceEnabled = true
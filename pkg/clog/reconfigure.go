@@ -0,0 +1,192 @@
+// Package clog: runtime reconfiguration of a running logger.
+package clog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// ErrNotRunning is returned by Reconfigure, Flush and SetFacilityLevel when
+// the logger has not been initialized or has been shut down.
+var ErrNotRunning = errors.New("logger is not running")
+
+// ErrReentrant is returned by Reconfigure and Flush when called from the
+// logger's own goroutines (a Hook, OnSinkError or a sink): they wait for
+// the agent, which would be waiting for the call to return.
+var ErrReentrant = errors.New("called from inside the logger")
+
+// control is a request executed on the agent goroutine between two events.
+// It travels through the queue like an event, so it takes effect after every
+// event logged before it and before every event logged after it.
+type control struct {
+	apply func()
+	done  chan struct{}
+}
+
+// Reconfigure applies cfg to the default logger. See Logger.Reconfigure.
+func Reconfigure(cfg Config) error {
+	return Default().Reconfigure(cfg)
+}
+
+// Reconfigure applies cfg to the running logger without restarting it. The
+// new Config is validated and its sinks are built first; on error nothing
+// changes. The agent then swaps in the new sinks, hooks, dedupe settings,
+// facility levels (discarding SetFacilityLevel overrides), redaction
+// patterns, drop policy, caller and context settings between two events: every event
+// logged before the call goes to the old sinks, every event logged after it
+// to the new ones. Retired sinks are drained, flushed and closed before
+// Reconfigure returns, and a new sink with the same name (or the same
+// SinkConfig.Instance) buffers its events until then, so a file or instance
+// is never written out of order or by two goroutines at once. The queue and stats are kept; the audio writer is
+// replaced only when Config.Audio changed. QueueSize, PriorityReserve and
+// Synchronous cannot change at runtime. It returns ErrReentrant when called
+// from a Hook or OnSinkError.
+func (l *Logger) Reconfigure(cfg Config) error {
+	if l == nil {
+		return ErrNotRunning
+	}
+	return l.agent.reconfigure(cfg, false)
+}
+
+// reconfigure implements Logger.Reconfigure. With inPlace (a WatchConfig
+// reload, whose cfg keeps the running hooks and OnSinkError), only what
+// changed is replaced: running sinks whose name and settings are unchanged
+// keep their workers, files and quarantine, hooks keep their quarantine, and
+// the dedupe state, redaction patterns and facility level table (with its
+// SetFacilityLevel overrides) are kept unless their settings changed.
+func (a *agent) reconfigure(cfg Config, inPlace bool) error {
+	if a.own.current() {
+		return ErrReentrant
+	}
+	a.reconfMu.Lock()
+	defer a.reconfMu.Unlock()
+
+	cur := a.cfg.Load()
+	if cfg.QueueSize != cur.QueueSize || cfg.PriorityReserve != cur.PriorityReserve {
+		return fmt.Errorf("queue size cannot change at runtime (running %d+%d, got %d+%d)",
+			cur.QueueSize, cur.PriorityReserve, cfg.QueueSize, cfg.PriorityReserve)
+	}
+	if cfg.Synchronous != cur.Synchronous {
+		return fmt.Errorf("synchronous mode cannot change at runtime")
+	}
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	// Reconfigure calls are serialized, so the active sinks are the ones the
+	// control request will retire.
+	var active []Sink
+	if p := a.activeSinks.Load(); p != nil {
+		active = *p
+	}
+	var keep func(string, interface{}) Sink
+	if inPlace && cfg.MaxConsecutivePanics == cur.MaxConsecutivePanics {
+		keep = keepRunning(active)
+	}
+	built, err := buildSinks(cfg, keep)
+	if err != nil {
+		return err
+	}
+	audioChanged := cfg.Audio != cur.Audio
+	var audio audioOutput
+	if audioChanged {
+		if audio, err = newAudioOutput(cfg.Audio); err != nil {
+			closeSinks(built)
+			return err
+		}
+	}
+	sinks := startSinks(built, a.stats, a.synchronous, a.newSinkSupervisor(cfg), active)
+
+	var retired []Sink
+	c := &control{done: make(chan struct{})}
+	c.apply = func() {
+		if !inPlace || !reflect.DeepEqual(cfg.Dedupe, cur.Dedupe) {
+			// Close out the dedupe run with the sinks and hooks that saw it.
+			a.flushDedupeSummary()
+			a.dedupe = newDedupeState(cfg.Dedupe)
+		}
+		if !inPlace || !reflect.DeepEqual(cfg.Redaction, cur.Redaction) {
+			a.redactor = newConfigRedactor(cfg.Redaction)
+		}
+		if !inPlace {
+			a.hooks = newHookSet(cfg.Hooks, a.stats)
+		}
+		a.cfg.Store(&cfg)
+		if !inPlace || !reflect.DeepEqual(cfg.FacilityLevels, cur.FacilityLevels) {
+			a.levelsMu.Lock()
+			a.levels.Store(newLevelTable(cfg.FacilityLevels))
+			a.levelsMu.Unlock()
+		}
+		a.mu.Lock()
+		retired = without(a.sinks, sinks)
+		a.sinks = sinks
+		a.mu.Unlock()
+		a.activeSinks.Store(&sinks)
+		if a.synchronous {
+			// Sinks are written under syncMu; close the retired ones before
+			// any caller can write to their replacements.
+			closeStartedSinks(context.Background(), retired)
+			retired = nil
+		}
+	}
+	if a.runControl(context.Background(), c) != nil {
+		closeStartedSinks(context.Background(), without(sinks, active))
+		if audio != nil {
+			_ = audio.Close()
+		}
+		return ErrNotRunning
+	}
+	closeStartedSinks(context.Background(), retired)
+
+	if audioChanged {
+		a.audioMu.Lock()
+		old := a.audioWriter
+		a.audioWriter = audio
+		a.audioMu.Unlock()
+		if old != nil {
+			_ = old.Flush()
+			_ = old.Close()
+		}
+	}
+	return nil
+}
+
+// runControl queues c behind every event already queued and waits until the
+// agent has applied it (a synchronous logger applies it inline). Control
+// requests bypass shedding and drop policies: they wait for room and are
+// never evicted.
+// It returns ErrNotRunning if the agent stopped before applying c, or ctx's
+// error if ctx ended first (c may still be applied later).
+func (a *agent) runControl(ctx context.Context, c *control) error {
+	if a.closed.Load() {
+		return ErrNotRunning
+	}
+	// Catastrophe level: control requests never count as below Error.
+	e := Event{Level: LevelCatastrophe, ctl: c}
+	if a.synchronous {
+		if !a.handleInline(e) {
+			return ErrNotRunning
+		}
+		return nil
+	}
+	switch a.queue.await(e, nil, ctx.Done()) {
+	case offerClosed:
+		return ErrNotRunning
+	case offerFull:
+		return ctx.Err()
+	}
+	select {
+	case <-c.done:
+		return nil
+	case <-a.exited:
+		select {
+		case <-c.done:
+			return nil
+		default:
+			return ErrNotRunning
+		}
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,255 @@
+package clog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fileOnlyConfig returns a Config that writes every level to dir/all.log.
+func fileOnlyConfig(dir string, hook Hook) Config {
+	cfg := DefaultConfig()
+	cfg.Console.Enabled = false
+	cfg.Dedupe.Enabled = false
+	cfg.File.BaseDir = dir
+	cfg.File.PerLevel = map[Level]string{}
+	for level := LevelDebug; level <= LevelCatastrophe; level++ {
+		cfg.File.PerLevel[level] = "all.log"
+	}
+	cfg.Hooks.Global = []Hook{hook}
+	return cfg
+}
+
+func readLog(t *testing.T, dir string) string {
+	t.Helper()
+	b, err := os.ReadFile(filepath.Join(dir, "all.log"))
+	if err != nil {
+		t.Fatalf("read log: %v", err)
+	}
+	return string(b)
+}
+
+func TestReconfigure_SwapsSinksAndHooksBetweenEvents(t *testing.T) {
+	dir1, dir2 := t.TempDir(), t.TempDir()
+	hook1, hook2 := &testHook{}, &testHook{}
+	l, err := New(fileOnlyConfig(dir1, hook1))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	l.Info("Test", "before")
+	if err := l.Reconfigure(fileOnlyConfig(dir2, hook2)); err != nil {
+		t.Fatalf("Reconfigure: %v", err)
+	}
+	// Retired sinks are closed by the time Reconfigure returns.
+	if got := readLog(t, dir1); !strings.Contains(got, "before") {
+		t.Errorf("old file = %q, want the event logged before Reconfigure", got)
+	}
+	l.Info("Test", "after")
+	l.Shutdown(context.Background())
+
+	if got := readLog(t, dir1); strings.Contains(got, "after") {
+		t.Errorf("old file received an event logged after Reconfigure: %q", got)
+	}
+	if got := readLog(t, dir2); !strings.Contains(got, "after") || strings.Contains(got, "before") {
+		t.Errorf("new file = %q, want only the event logged after Reconfigure", got)
+	}
+	if len(hook1.getEvents()) != 1 || len(hook2.getEvents()) != 1 {
+		t.Errorf("hook events = %d/%d, want 1/1", len(hook1.getEvents()), len(hook2.getEvents()))
+	}
+	if stats := l.Stats(); stats.AcceptedCount != 2 || stats.EmittedCount != 2 {
+		t.Errorf("stats = %+v, want counters kept across Reconfigure", stats)
+	}
+}
+
+func TestReconfigure_RejectsInvalidConfig(t *testing.T) {
+	hook := &testHook{}
+	cfg := DefaultConfig()
+	cfg.Console.Enabled = false
+	cfg.Hooks.Global = []Hook{hook}
+	l, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	bad := cfg
+	bad.QueueSize = 10
+	if err := l.Reconfigure(bad); err == nil || !strings.Contains(err.Error(), "queue size") {
+		t.Errorf("QueueSize change: err = %v", err)
+	}
+	bad = cfg
+	bad.DropPolicy = "maybe"
+	if err := l.Reconfigure(bad); err == nil {
+		t.Error("unknown drop policy accepted")
+	}
+	bad = cfg
+	bad.File.BaseDir = filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(bad.File.BaseDir, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Reconfigure(bad); err == nil {
+		t.Error("unusable file BaseDir accepted")
+	}
+
+	l.Info("Test", "still running")
+	l.Shutdown(context.Background())
+	if len(hook.getEvents()) != 1 {
+		t.Errorf("old configuration lost after failed Reconfigure")
+	}
+	if err := l.Reconfigure(cfg); !errors.Is(err, ErrNotRunning) {
+		t.Errorf("Reconfigure after Shutdown: err = %v, want ErrNotRunning", err)
+	}
+}
+
+func TestReconfigure_ConcurrentLogging(t *testing.T) {
+	hook := &testHook{}
+	cfg := DefaultConfig()
+	cfg.Console.Enabled = false
+	cfg.Dedupe.Enabled = false
+	cfg.ShedThresholds = nil
+	cfg.DropPolicy = "block_forever"
+	cfg.Hooks.Global = []Hook{hook}
+	l, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				l.Debug("Test", "event %d", i)
+			}
+		}()
+	}
+	for i := 0; i < 20; i++ {
+		next := cfg
+		next.Dedupe.Enabled = i%2 == 0
+		next.Dedupe.SummaryFormat = "repeated %d"
+		if err := l.Reconfigure(next); err != nil {
+			t.Fatalf("Reconfigure: %v", err)
+		}
+	}
+	wg.Wait()
+	l.Shutdown(context.Background())
+
+	if stats := l.Stats(); stats.AcceptedCount != 800 {
+		t.Errorf("accepted %d events, want 800", stats.AcceptedCount)
+	}
+}
+
+func TestReconfigure_NotInitialized(t *testing.T) {
+	Shutdown(context.Background())
+	if err := Reconfigure(DefaultConfig()); !errors.Is(err, ErrNotRunning) {
+		t.Errorf("Reconfigure before Init: err = %v, want ErrNotRunning", err)
+	}
+}
+
+// reentrantHook calls Flush and Reconfigure on the logger it runs in.
+type reentrantHook struct {
+	l                   *Logger
+	cfg                 Config
+	flushErr, reconfErr chan error
+}
+
+func (h *reentrantHook) OnLog(e Event) {
+	if e.Message != "trigger" {
+		return
+	}
+	h.flushErr <- h.l.Flush(context.Background())
+	h.reconfErr <- h.l.Reconfigure(h.cfg)
+}
+
+func TestReconfigure_FromHookReturnsErrReentrant(t *testing.T) {
+	hook := &reentrantHook{flushErr: make(chan error, 1), reconfErr: make(chan error, 1)}
+	cfg := DefaultConfig()
+	cfg.Console.Enabled = false
+	cfg.Hooks.Global = []Hook{hook}
+	l, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer l.Shutdown(context.Background())
+	hook.l, hook.cfg = l, cfg
+
+	l.Info("Test", "trigger")
+	for name, ch := range map[string]chan error{"Flush": hook.flushErr, "Reconfigure": hook.reconfErr} {
+		select {
+		case err := <-ch:
+			if !errors.Is(err, ErrReentrant) {
+				t.Errorf("%s from a hook: err = %v, want ErrReentrant", name, err)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("%s from a hook did not return", name)
+		}
+	}
+	if err := l.Flush(context.Background()); err != nil {
+		t.Errorf("Flush after the hook: %v", err)
+	}
+}
+
+// unsyncedSink records messages without locking: it is only safe if one
+// goroutine at a time writes it, as the delivery workers promise.
+type unsyncedSink struct {
+	msgs []string
+}
+
+func (s *unsyncedSink) Write(level Level, iface, formatted string) {
+	time.Sleep(50 * time.Microsecond) // keep the retired worker busy
+	s.msgs = append(s.msgs, formatted)
+}
+func (s *unsyncedSink) Flush() {}
+func (s *unsyncedSink) Close() {}
+
+func TestReconfigure_InstanceKeptAcrossReconfigureWrittenInOrder(t *testing.T) {
+	for _, synchronous := range []bool{false, true} {
+		t.Run(fmt.Sprintf("synchronous=%v", synchronous), func(t *testing.T) {
+			sink := &unsyncedSink{}
+			cfg := panicsConfig(synchronous)
+			cfg.Sinks = []SinkConfig{{Name: "kept", Instance: sink}}
+			l, err := New(cfg)
+			if err != nil {
+				t.Fatalf("New: %v", err)
+			}
+			// Log while Reconfigure runs, so the new worker gets events while
+			// the retired one still has a backlog.
+			halfway := make(chan struct{})
+			logged := make(chan struct{})
+			go func() {
+				defer close(logged)
+				for i := 0; i < 200; i++ {
+					if i == 100 {
+						close(halfway)
+					}
+					if i >= 100 {
+						time.Sleep(20 * time.Microsecond)
+					}
+					l.Info("T", "m%d", i)
+				}
+			}()
+			<-halfway
+			if err := l.Reconfigure(cfg); err != nil {
+				t.Fatalf("Reconfigure: %v", err)
+			}
+			<-logged
+			l.Shutdown(context.Background())
+
+			if len(sink.msgs) != 200 {
+				t.Fatalf("sink got %d messages, want 200", len(sink.msgs))
+			}
+			for i, m := range sink.msgs {
+				if want := fmt.Sprintf("m%d", i); m != want {
+					t.Fatalf("message %d = %q, want %q", i, m, want)
+				}
+			}
+		})
+	}
+}
@@ -91,6 +91,18 @@ func NewRedactor(patterns []RedactPattern) *Redactor {
 	return &Redactor{patterns: compiled}
 }
 
+// checkRedactPattern reports whether p can be compiled by NewRedactor. An
+// empty Regex is rejected: it would match between every character.
+func checkRedactPattern(p RedactPattern) error {
+	if p.Regex == "" {
+		return fmt.Errorf("pattern %q: empty regex", p.Name)
+	}
+	if _, err := regexp.Compile(p.Regex); err != nil {
+		return fmt.Errorf("pattern %q: %w", p.Name, err)
+	}
+	return nil
+}
+
 // Redact applies every pattern in order and returns the scrubbed string. It is
 // safe for concurrent use. The redaction is idempotent for the default pattern
 // set: the replacement tokens (<email>, <ip>, <phone>) contain no characters
@@ -108,9 +120,11 @@ func (r *Redactor) Redact(s string) string {
 	s = boundString(s)
 	for i := range r.patterns {
 		p := &r.patterns[i]
-		// ReplaceAllString returns the input unchanged (no new allocation) when
-		// there is no match, keeping the no-PII common case cheap.
-		s = p.re.ReplaceAllString(s, p.replacement)
+		// ReplaceAllString allocates even when nothing matches; MatchString
+		// does not, keeping the no-PII common case allocation-free.
+		if p.re.MatchString(s) {
+			s = p.re.ReplaceAllString(s, p.replacement)
+		}
 	}
 	return s
 }
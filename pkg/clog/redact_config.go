@@ -6,7 +6,6 @@ package clog
 import (
 	"os"
 	"regexp"
-	"strings"
 	"sync"
 	"sync/atomic"
 )
@@ -14,7 +13,7 @@ import (
 // redactEnvVar is the environment variable that toggles redaction. It is read
 // once at package init. Any of "0", "false", "no", "off" (case-insensitive)
 // disables redaction; anything else (including unset) leaves it enabled.
-const redactEnvVar = "CORALIE_LOG_REDACT"
+const redactEnvVar = envPrefix + "REDACT"
 
 var (
 	// defaultRedactor is the package-level redactor used by the Redact helper
@@ -37,12 +36,8 @@ func init() {
 // CORALIE_LOG_REDACT environment variable. Default (unset or unrecognized) is
 // enabled; only explicit falsey values disable it.
 func redactEnabledFromEnv() bool {
-	switch strings.ToLower(strings.TrimSpace(os.Getenv(redactEnvVar))) {
-	case "0", "false", "no", "off":
-		return false
-	default:
-		return true
-	}
+	enabled, err := parseEnvBool(os.Getenv(redactEnvVar))
+	return enabled || err != nil
 }
 
 // defaultPatterns returns the ordered default PII pattern set. Ordering rationale:
@@ -117,6 +112,37 @@ func RedactionEnabled() bool {
 	return redactEnabled.Load()
 }
 
+// configRedactor applies a logger's Config.Redaction patterns.
+type configRedactor struct {
+	extra           *Redactor
+	replaceDefaults bool
+}
+
+// newConfigRedactor compiles rc, which must be validated. It returns nil when
+// rc adds nothing, leaving the package-level redactor alone in charge.
+func newConfigRedactor(rc RedactionConfig) *configRedactor {
+	if len(rc.Patterns) == 0 && !rc.ReplaceDefaults {
+		return nil
+	}
+	return &configRedactor{extra: NewRedactor(rc.Patterns), replaceDefaults: rc.ReplaceDefaults}
+}
+
+// redact scrubs s with the package-level redactor and then the logger's own
+// patterns (or only those with ReplaceDefaults), honoring the global toggle.
+func (a *agent) redact(s string) string {
+	r := a.redactor
+	if r == nil {
+		return Redact(s)
+	}
+	if !redactEnabled.Load() {
+		return s
+	}
+	if !r.replaceDefaults {
+		s = Redact(s)
+	}
+	return r.extra.Redact(s)
+}
+
 // SetRedactor replaces the package-level default redactor used by Redact. Pass a
 // custom Redactor (e.g. from NewRedactor) to tune patterns at runtime. A nil
 // argument is ignored.
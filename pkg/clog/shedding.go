@@ -0,0 +1,39 @@
+// Package clog: priority-aware load shedding ladder.
+package clog
+
+import "fmt"
+
+// DefaultShedThresholds returns a shedding ladder to put in
+// Config.ShedThresholds: Debug is shed once the queue is half full, Info at
+// 75%, Success at 90%. Warning and above only drop when the queue is
+// actually full.
+func DefaultShedThresholds() map[Level]float64 {
+	return map[Level]float64{
+		LevelDebug:   0.5,
+		LevelInfo:    0.75,
+		LevelSuccess: 0.9,
+	}
+}
+
+// validateShedding rejects out-of-range thresholds.
+func validateShedding(cfg Config) error {
+	for level, ratio := range cfg.ShedThresholds {
+		if !(ratio > 0 && ratio <= 1) {
+			return fmt.Errorf("shed threshold for %s must be in (0, 1], got %v", level, ratio)
+		}
+	}
+	return nil
+}
+
+// shouldShed reports whether an event at level must be refused because the
+// queue is filled past that level's share of QueueSize. The blocking
+// policies never shed.
+func (a *agent) shouldShed(level Level) bool {
+	cfg := a.cfg.Load()
+	ratio, ok := cfg.ShedThresholds[level]
+	if !ok || cfg.QueueSize <= 0 ||
+		cfg.DropPolicy == dropPolicyBlock || cfg.DropPolicy == dropPolicyBlockForever {
+		return false
+	}
+	return float64(a.queue.len()) >= ratio*float64(cfg.QueueSize)
+}
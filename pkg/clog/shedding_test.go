@@ -0,0 +1,183 @@
+package clog
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// newParkedLogger returns a Logger whose agent is parked in a gate hook on a
+// first "in flight" event, so later events stay queued until release.
+func newParkedLogger(t *testing.T, cfg Config) (*Logger, *gateHook) {
+	t.Helper()
+	hook := newGateHook()
+	cfg.Console.Enabled = false
+	cfg.Dedupe.Enabled = false
+	cfg.Hooks.Global = []Hook{hook}
+	l, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	l.Warning("Test", "in flight")
+	<-hook.started
+	return l, hook
+}
+
+func TestShedding_LadderAndReserve(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.QueueSize = 10
+	cfg.PriorityReserve = 2
+	cfg.ShedThresholds = DefaultShedThresholds()
+	l, hook := newParkedLogger(t, cfg)
+
+	logN := func(level Level, n int) {
+		for i := 0; i < n; i++ {
+			l.log(level, "Test", "flood", nil)
+		}
+	}
+	logN(LevelDebug, 10)      // 5 queued (< 50%), 5 shed
+	logN(LevelInfo, 5)        // 3 queued (< 75%), 2 shed
+	logN(LevelSuccess, 5)     // 1 queued (< 90%), 4 shed
+	logN(LevelWarning, 3)     // 1 queued (QueueSize reached), 2 dropped
+	logN(LevelError, 2)       // both fit in the reserve
+	logN(LevelCatastrophe, 1) // reserve exhausted: dropped
+
+	close(hook.release)
+	l.Shutdown(context.Background())
+
+	stats := l.Stats()
+	wantShed := map[Level]int64{LevelDebug: 5, LevelInfo: 2, LevelSuccess: 4}
+	wantDrops := map[Level]int64{LevelWarning: 2, LevelCatastrophe: 1}
+	for level := LevelDebug; level <= LevelCatastrophe; level++ {
+		if got := stats.ShedPerLevel[level]; got != wantShed[level] {
+			t.Errorf("ShedPerLevel[%s] = %d, want %d", level, got, wantShed[level])
+		}
+		if got := stats.DropsPerLevel[level]; got != wantDrops[level] {
+			t.Errorf("DropsPerLevel[%s] = %d, want %d", level, got, wantDrops[level])
+		}
+	}
+
+	events := hook.getEvents()
+	if len(events) != 13 {
+		t.Fatalf("delivered %d events, want 13", len(events))
+	}
+	for i := 1; i < len(events); i++ {
+		if events[i].Seq <= events[i-1].Seq {
+			t.Fatalf("events out of order at %d: seq %d after %d", i, events[i].Seq, events[i-1].Seq)
+		}
+	}
+	if events[11].Level != LevelError || events[12].Level != LevelError {
+		t.Errorf("last events = %s, %s, want the two errors", events[11].Level, events[12].Level)
+	}
+}
+
+func TestShedding_OffByDefaultAndUnderBlockingPolicies(t *testing.T) {
+	if cfg := DefaultConfig(); cfg.ShedThresholds != nil {
+		t.Errorf("DefaultConfig sheds %v, want no shedding", cfg.ShedThresholds)
+	}
+
+	cfg := DefaultConfig()
+	cfg.QueueSize = 4
+	cfg.ShedThresholds = DefaultShedThresholds()
+	cfg.DropPolicy = "block"
+	cfg.BlockTimeout = time.Millisecond
+	l, hook := newParkedLogger(t, cfg)
+	for i := 0; i < 5; i++ {
+		l.Debug("Test", "d%d", i) // the fifth waits for room, then gives up
+	}
+	close(hook.release)
+	l.Shutdown(context.Background())
+
+	stats := l.Stats()
+	if stats.ShedPerLevel[LevelDebug] != 0 || stats.TimeoutDropsPerLevel[LevelDebug] != 1 {
+		t.Errorf("ShedPerLevel = %v, TimeoutDropsPerLevel = %v, want no shedding and 1 timeout",
+			stats.ShedPerLevel, stats.TimeoutDropsPerLevel)
+	}
+	if n := len(hook.getEvents()); n != 5 {
+		t.Errorf("delivered %d events, want 5", n)
+	}
+}
+
+func TestShedding_DefaultConfigKeepsErrorsBehindDebugFlood(t *testing.T) {
+	for _, policy := range []string{"drop_new", "drop_old"} {
+		t.Run(policy, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.DropPolicy = policy
+			l, hook := newParkedLogger(t, cfg)
+			l.Catastrophe("Test", "first")
+			for i := 0; i < cfg.QueueSize+10; i++ {
+				l.Debug("Test", "flood")
+			}
+			l.Error("Test", "last")
+			close(hook.release)
+			l.Shutdown(context.Background())
+
+			stats := l.Stats()
+			if stats.DropsPerLevel[LevelError] != 0 || stats.DropsPerLevel[LevelCatastrophe] != 0 {
+				t.Errorf("DropsPerLevel = %v, want no Error or Catastrophe dropped", stats.DropsPerLevel)
+			}
+			var errs int
+			for _, e := range hook.getEvents() {
+				if e.Level >= LevelError {
+					errs++
+				}
+			}
+			if errs != 2 {
+				t.Errorf("delivered %d Error/Catastrophe events, want 2", errs)
+			}
+		})
+	}
+}
+
+func TestShedding_DropOldKeepsErrors(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.QueueSize = 2
+	cfg.PriorityReserve = 1
+	cfg.DropPolicy = "drop_old"
+	cfg.ShedThresholds = nil
+	l, hook := newParkedLogger(t, cfg)
+
+	l.Error("Test", "error")
+	l.Debug("Test", "d1")
+	l.Debug("Test", "d2")
+	l.Debug("Test", "d3") // the error is moved to the tail and d1 is evicted
+	l.Debug("Test", "d4") // evicts d2
+
+	close(hook.release)
+	l.Shutdown(context.Background())
+
+	var got []string
+	for _, e := range hook.getEvents() {
+		got = append(got, e.Message)
+	}
+	want := []string{"in flight", "error", "d3", "d4"}
+	if len(got) != len(want) {
+		t.Fatalf("delivered %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("delivered %v, want %v", got, want)
+		}
+	}
+	stats := l.Stats()
+	if stats.DropsPerLevel[LevelError] != 0 {
+		t.Errorf("error dropped under drop_old")
+	}
+	if stats.DropsPerLevel[LevelDebug] != 2 {
+		t.Errorf("DropsPerLevel[Debug] = %d, want the 2 evicted", stats.DropsPerLevel[LevelDebug])
+	}
+}
+
+func TestShedding_Validation(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Console.Enabled = false
+	cfg.ShedThresholds = map[Level]float64{LevelDebug: 1.5}
+	if _, err := New(cfg); err == nil {
+		t.Error("threshold 1.5 accepted")
+	}
+	cfg.ShedThresholds = nil
+	cfg.PriorityReserve = -1
+	if _, err := New(cfg); err == nil {
+		t.Error("negative reserve accepted")
+	}
+}
@@ -5,12 +5,96 @@ package clog
 // The agent calls Write for each event; the sink may apply level filtering
 // and formatting internally. Flush and Close are called during Shutdown;
 // sinks that do not need them may use no-op implementations.
+//
+// A plain Sink receives an event's structured fields appended to formatted as
+// " key=value" pairs. Sinks that want the fields separately implement
+// EventSink.
 type Sink interface {
 	Write(level Level, iface, formatted string)
 	Flush()
 	Close()
 }
 
+// EventSink is an optional extension of Sink. When a sink implements it, the
+// agent calls WriteEvent instead of Write with the fully processed Event: the
+// redacted formatted Message and the redacted structured Fields (Params is
+// always nil). All built-in sinks implement EventSink.
+type EventSink interface {
+	Sink
+	WriteEvent(e Event)
+}
+
+// BatchSink is an optional extension of Sink for sinks that write many events
+// at once, such as network sinks. When a sink implements it, its delivery
+// worker collects up to DeliveryConfig.MaxBatchSize buffered events, waiting
+// at most MaxBatchLinger after the first one for more, and calls WriteBatch
+// instead of WriteEvent. The events are fully processed as for EventSink and
+// in log order; the slice is only valid until WriteBatch returns. A
+// synchronous logger passes each event as a batch of one. A non-nil error is
+// reported as a SinkError for the whole batch.
+type BatchSink interface {
+	Sink
+	WriteBatch(events []Event) error
+}
+
+// LevelEnabler is an optional extension of Sink for sinks that filter by
+// level (or facility). Enabled reports whether the sink would write an event
+// at level for the facility iface; it must be cheap and safe to call from
+// any goroutine. Sinks that do not implement it are assumed to write every
+// event. All built-in sinks implement it; clog.Enabled and Lazy params
+// consult it.
+type LevelEnabler interface {
+	Enabled(level Level, iface string) bool
+}
+
+// ErrorFlusher is an optional extension of Sink for sinks whose flush can
+// fail (e.g. fsync errors). When a sink implements it, FlushErr is called
+// instead of Flush by Logger.Flush, and its error is reported in FlushError.
+type ErrorFlusher interface {
+	FlushErr() error
+}
+
+// ErrorWriter is an optional extension of Sink for sinks whose writes can
+// fail (e.g. an HTTP sink). When a sink implements it, WriteErr is called
+// instead of WriteEvent or Write with the same processed Event, and a non-nil
+// error is reported as a SinkError.
+type ErrorWriter interface {
+	WriteErr(e Event) error
+}
+
+// ErrorCloser is an optional extension of Sink for sinks whose close can
+// fail. When a sink implements it, CloseErr is called instead of Close and a
+// non-nil error is reported as a SinkError.
+type ErrorCloser interface {
+	CloseErr() error
+}
+
+// flushSink flushes s, returning its error when it implements ErrorFlusher.
+func flushSink(s Sink) error {
+	if ef, ok := s.(ErrorFlusher); ok {
+		return ef.FlushErr()
+	}
+	s.Flush()
+	return nil
+}
+
+// closeSink closes s, returning its error when it implements ErrorCloser.
+func closeSink(s Sink) error {
+	if ec, ok := s.(ErrorCloser); ok {
+		return ec.CloseErr()
+	}
+	s.Close()
+	return nil
+}
+
+// sinkEnabled reports whether s would write an event at level for iface.
+func sinkEnabled(s Sink, level Level, iface string) bool {
+	if le, ok := s.(LevelEnabler); ok {
+		return le.Enabled(level, iface)
+	}
+	return true
+}
+
 // levelFilter returns true if the event should be written given minLevel and omitSet.
 // If minLevel is set (e.g. LevelInfo), only levels >= minLevel pass.
 // If omitSet[level] is true, the event is dropped. OmitSet takes precedence.
@@ -0,0 +1,232 @@
+// Package clog: building the configured sinks.
+package clog
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+// namedSink is a configured sink before its delivery worker is started, or
+// a running sink kept by buildSinks.
+type namedSink struct {
+	name     string
+	sink     Sink
+	delivery DeliveryConfig
+	levels   sinkLevels
+	spec     interface{} // what it is built from: see sinkSpec
+	started  Sink        // a kept running sink; sink is nil then
+}
+
+// sinkLevels is the MinLevel and OmitLevels filter of a SinkConfig, applied
+// by the delivery wrapper so that it holds for every kind of sink.
+type sinkLevels struct {
+	min  Level
+	omit map[Level]bool
+}
+
+// allow reports whether an event at level passes the filter.
+func (l sinkLevels) allow(level Level) bool {
+	return levelFilter(level, l.min, l.omit)
+}
+
+// buildSinks creates the console, file and extra sinks from a validated cfg.
+// Names are made unique ("betterstack", "betterstack#2", ...) for per-sink
+// stats. keep, when not nil, may return a running sink to use instead of
+// building a new one with that name and spec. On error, sinks already built
+// are closed.
+func buildSinks(cfg Config, keep func(name string, spec interface{}) Sink) ([]namedSink, error) {
+	var out []namedSink
+	used := make(map[string]int)
+	add := func(base string, spec interface{}, d DeliveryConfig, levels sinkLevels, build func() (Sink, error)) error {
+		name := base
+		if n := used[base] + 1; n > 1 {
+			name += "#" + strconv.Itoa(n)
+		}
+		if keep != nil {
+			if s := keep(name, spec); s != nil {
+				used[base]++
+				out = append(out, namedSink{name: name, spec: spec, started: s})
+				return nil
+			}
+		}
+		s, err := build()
+		if err != nil || s == nil {
+			return err
+		}
+		used[base]++
+		out = append(out, namedSink{name: name, sink: s, delivery: d, levels: levels, spec: spec})
+		return nil
+	}
+
+	var err error
+	if cfg.Console.Enabled {
+		err = add("console", cfg.Console, cfg.Console.Delivery, sinkLevels{}, func() (Sink, error) {
+			return newConsoleSink(cfg.Console), nil
+		})
+	}
+	if err == nil && cfg.File.BaseDir != "" {
+		err = add("file", cfg.File, cfg.File.Delivery, sinkLevels{}, func() (Sink, error) {
+			fs, err := newFileSink(cfg.File)
+			if fs == nil {
+				return nil, err
+			}
+			return fs, err
+		})
+	}
+	// Additional sinks from Config.Sinks (e.g. BetterStack)
+	for _, c := range cfg.Sinks {
+		if err != nil {
+			break
+		}
+		err = add(c.name(), c, c.Delivery, sinkLevels{min: c.MinLevel, omit: c.OmitLevels}, func() (Sink, error) {
+			return buildExtraSink(c)
+		})
+	}
+	if err != nil {
+		closeSinks(out)
+		return nil, err
+	}
+	return out, nil
+}
+
+// keepRunning returns a keep func for buildSinks that hands back each of the
+// running sinks at most once, when its name and spec are unchanged.
+func keepRunning(running []Sink) func(name string, spec interface{}) Sink {
+	taken := make(map[Sink]bool)
+	return func(name string, spec interface{}) Sink {
+		for _, s := range running {
+			n, sp := sinkOrigin(s)
+			if !taken[s] && n == name && sameSpec(sp, spec) {
+				taken[s] = true
+				return s
+			}
+		}
+		return nil
+	}
+}
+
+// sinkOrigin returns the name and spec a started sink was built from.
+func sinkOrigin(s Sink) (string, interface{}) {
+	switch s := s.(type) {
+	case *asyncSink:
+		return s.name, s.spec
+	case *syncSink:
+		return s.name, s.spec
+	}
+	return "", nil
+}
+
+// sameSpec reports whether two sink specs (a ConsoleConfig, FileConfig or
+// SinkConfig) would build the same sink. A SinkConfig.Instance must be the
+// very same instance.
+func sameSpec(a, b interface{}) bool {
+	ca, ok1 := a.(SinkConfig)
+	cb, ok2 := b.(SinkConfig)
+	if ok1 != ok2 {
+		return false
+	}
+	if ok1 {
+		if (ca.Instance != nil || cb.Instance != nil) && !identical(ca.Instance, cb.Instance) {
+			return false
+		}
+		ca.Instance, cb.Instance = nil, nil
+		return reflect.DeepEqual(ca, cb)
+	}
+	return a != nil && reflect.DeepEqual(a, b)
+}
+
+// buildExtraSink builds one sink from a SinkConfig: its Instance, or one made
+// by the factory registered for its Type. It returns nil for a sink disabled
+// by its config (e.g. no BetterStack token).
+func buildExtraSink(c SinkConfig) (Sink, error) {
+	if c.Instance != nil {
+		return borrowedSink{inner: c.Instance}, nil
+	}
+	factory, ok := sinkFactory(c.Type)
+	if !ok {
+		return nil, fmt.Errorf("unknown sink type %q", c.Type)
+	}
+	s, err := factory(c)
+	if err != nil {
+		return nil, fmt.Errorf("sink %s: %w", c.name(), err)
+	}
+	return s, nil
+}
+
+// startSinks wraps every built sink in an asyncSink with its own buffer,
+// worker goroutine and stats, or, for a synchronous logger, in a syncSink
+// that writes inline with the same stats. Errors and panics go to sup.
+// The wrappers drop events filtered out by the sink's levels.
+//
+// retired are the sinks being replaced (see Reconfigure). A worker does not
+// start writing until the retired worker with the same name, or for the
+// same SinkConfig.Instance, has drained and closed its sink, so a file or an
+// instance is never written by two workers at once or out of order.
+func startSinks(built []namedSink, stats *statsState, synchronous bool, sup *sinkSupervisor, retired []Sink) []Sink {
+	out := make([]Sink, 0, len(built))
+	for _, b := range built {
+		if b.started != nil {
+			out = append(out, b.started)
+			continue
+		}
+		if synchronous {
+			s := newSyncSink(b.name, b.sink, b.delivery, stats.sink(b.name), sup)
+			s.levels, s.spec = b.levels, b.spec
+			out = append(out, s)
+			continue
+		}
+		var after []<-chan struct{}
+		for _, r := range retired {
+			if rs, ok := r.(*asyncSink); ok && (rs.name == b.name || sameInstance(rs.inner, b.sink)) {
+				after = append(after, rs.done)
+			}
+		}
+		s := newAsyncSinkAfter(b.name, b.sink, b.delivery, stats.sink(b.name), sup, after)
+		s.levels, s.spec = b.levels, b.spec
+		out = append(out, s)
+	}
+	return out
+}
+
+// sameInstance reports whether a and b borrow the same SinkConfig.Instance.
+func sameInstance(a, b Sink) bool {
+	ba, ok1 := a.(borrowedSink)
+	bb, ok2 := b.(borrowedSink)
+	return ok1 && ok2 && identical(ba.inner, bb.inner)
+}
+
+// identical reports whether a and b are the same sink value. Values of
+// types that cannot be compared are never identical.
+func identical(a, b Sink) bool {
+	if a == nil || b == nil || reflect.TypeOf(a) != reflect.TypeOf(b) ||
+		!reflect.TypeOf(a).Comparable() {
+		return false
+	}
+	return a == b
+}
+
+// closeSinks closes sinks that were built but never started; kept running
+// sinks are left alone.
+func closeSinks(built []namedSink) {
+	for _, b := range built {
+		if b.sink != nil {
+			b.sink.Close()
+		}
+	}
+}
+
+// without returns the sinks in from that are not in keep.
+func without(from, keep []Sink) []Sink {
+	var out []Sink
+	for _, s := range from {
+		kept := false
+		for _, k := range keep {
+			kept = kept || s == k
+		}
+		if !kept {
+			out = append(out, s)
+		}
+	}
+	return out
+}
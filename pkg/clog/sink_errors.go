@@ -0,0 +1,129 @@
+// Package clog: sink error reporting and the internal diagnostics output.
+package clog
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// diagRepeatInterval is how often one fault is reported again while it
+// keeps happening.
+const diagRepeatInterval = 10 * time.Second
+
+// SinkError is a failed operation of one sink, passed to Config.OnSinkError.
+type SinkError struct {
+	Sink string // name in Stats.Sinks
+	Op   string // "write", "flush" or "close"
+	Err  error
+}
+
+// Error implements error.
+func (e *SinkError) Error() string {
+	return "sink " + e.Sink + ": " + e.Op + ": " + e.Err.Error()
+}
+
+// Unwrap returns the sink's error.
+func (e *SinkError) Unwrap() error {
+	return e.Err
+}
+
+// lastSinkError is the most recent error of a sink, for SinkStats.
+type lastSinkError struct {
+	msg string
+	at  time.Time
+}
+
+// recordSinkError counts err as a failed op of the named sink in c and hands
+// it to report (nil only counts). It does nothing for a nil err.
+func recordSinkError(name, op string, err error, c *sinkCounters, report func(*SinkError)) {
+	if err == nil {
+		return
+	}
+	se := &SinkError{Sink: name, Op: op, Err: err}
+	c.errors.Add(1)
+	c.lastError.Store(&lastSinkError{msg: se.Error(), at: time.Now()})
+	if report != nil {
+		report(se)
+	}
+}
+
+// newSinkSupervisor returns the supervisor for sinks started with cfg: every
+// error goes to the diagnostics output, then to cfg.OnSinkError, and a sink
+// quarantined after cfg's MaxConsecutivePanics is announced with a
+// Catastrophe event. A synchronous logger's sinks panic under syncMu, so
+// their events are queued as notices instead of being logged right away.
+func (a *agent) newSinkSupervisor(cfg Config) *sinkSupervisor {
+	onError := cfg.OnSinkError
+	sup := &sinkSupervisor{
+		report: func(err *SinkError) {
+			var pe panicValueError
+			if errors.As(err.Err, &pe) {
+				a.diagf(err.Sink+"/"+err.Op, "%v\n%s", err, pe.stack)
+			} else {
+				a.diagf(err.Sink+"/"+err.Op, "%v", err)
+			}
+			if onError != nil {
+				a.callOnSinkError(onError, err)
+			}
+		},
+		maxPanics: cfg.maxConsecutivePanics(),
+		notify:    a.submit,
+		own:       &a.own,
+	}
+	if cfg.Synchronous {
+		sup.notify = a.addNotice
+	}
+	return sup
+}
+
+// diagf reports a fault of the logger itself on Config.DiagnosticsOutput.
+// key identifies the fault for rate limiting.
+func (a *agent) diagf(key, format string, args ...interface{}) {
+	out := a.cfg.Load().DiagnosticsOutput
+	if out == nil {
+		out = os.Stderr
+	}
+	a.diag.printf(out, time.Now(), key, format, args...)
+}
+
+// diagnostics writes fault reports, one line each, and limits every fault
+// (by key) to one line per diagRepeatInterval. The next line after a quiet
+// period says how many reports were suppressed.
+type diagnostics struct {
+	mu     sync.Mutex
+	faults map[string]*diagFault
+}
+
+type diagFault struct {
+	last       time.Time
+	suppressed int
+}
+
+// printf writes one report to out unless key was reported less than
+// diagRepeatInterval before now.
+func (d *diagnostics) printf(out io.Writer, now time.Time, key, format string, args ...interface{}) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.faults == nil {
+		d.faults = make(map[string]*diagFault)
+	}
+	f := d.faults[key]
+	if f == nil {
+		f = &diagFault{}
+		d.faults[key] = f
+	} else if now.Sub(f.last) < diagRepeatInterval {
+		f.suppressed++
+		return
+	}
+	msg := fmt.Sprintf(format, args...)
+	if f.suppressed > 0 {
+		msg += fmt.Sprintf(" (%d similar suppressed)", f.suppressed)
+	}
+	f.last = now
+	f.suppressed = 0
+	_, _ = fmt.Fprintf(out, "clog: %s\n", msg)
+}
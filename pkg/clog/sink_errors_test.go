@@ -0,0 +1,121 @@
+package clog
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// failingSink fails every write and flush.
+type failingSink struct {
+	captureSink
+}
+
+func (s *failingSink) WriteErr(e Event) error {
+	return fmt.Errorf("write %q: %w", e.Message, errBoom)
+}
+
+func (s *failingSink) FlushErr() error { return errBoom }
+
+var errBoom = errors.New("boom")
+
+// syncBuffer is a bytes.Buffer safe for concurrent use.
+type syncBuffer struct {
+	mu sync.Mutex
+	b  bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.b.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.b.String()
+}
+
+func TestSinkErrors_CountedAndReported(t *testing.T) {
+	for _, synchronous := range []bool{false, true} {
+		t.Run(fmt.Sprintf("synchronous=%v", synchronous), func(t *testing.T) {
+			var mu sync.Mutex
+			var reported []*SinkError
+			diag := &syncBuffer{}
+			cfg := DefaultConfig()
+			cfg.Console.Enabled = false
+			cfg.Dedupe.Enabled = false
+			cfg.Synchronous = synchronous
+			if synchronous {
+				cfg.QueueSize = 0
+			}
+			cfg.Sinks = []SinkConfig{{Name: "bad", Instance: &failingSink{}}}
+			cfg.OnSinkError = func(err *SinkError) {
+				mu.Lock()
+				reported = append(reported, err)
+				mu.Unlock()
+			}
+			cfg.DiagnosticsOutput = diag
+			l, err := New(cfg)
+			if err != nil {
+				t.Fatalf("New: %v", err)
+			}
+			defer l.Shutdown(context.Background())
+
+			for i := 0; i < 3; i++ {
+				l.Info("T", "m%d", i)
+			}
+			if err := l.Flush(context.Background()); err == nil {
+				t.Error("Flush = nil, want the sink's flush error")
+			}
+
+			st := l.Stats().Sinks["bad"]
+			if st.Errors != 4 || st.Written != 3 {
+				t.Errorf("Errors = %d, Written = %d, want 4 and 3", st.Errors, st.Written)
+			}
+			if st.LastError != "sink bad: flush: boom" || st.LastErrorAt.IsZero() {
+				t.Errorf("LastError = %q at %v", st.LastError, st.LastErrorAt)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			var ops []string
+			for _, e := range reported {
+				if e.Sink != "bad" || !errors.Is(e, errBoom) {
+					t.Errorf("reported %#v", e)
+				}
+				ops = append(ops, e.Op)
+			}
+			if got := strings.Join(ops, ","); got != "write,write,write,flush" {
+				t.Errorf("OnSinkError ops = %s", got)
+			}
+			// One line per fault; the repeated write errors are held back.
+			want := "clog: sink bad: write: write \"m0\": boom\nclog: sink bad: flush: boom\n"
+			if got := diag.String(); got != want {
+				t.Errorf("diagnostics = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestDiagnostics_RateLimitsRepeats(t *testing.T) {
+	var d diagnostics
+	var out bytes.Buffer
+	t0 := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	d.printf(&out, t0, "a", "first %d", 1)
+	d.printf(&out, t0.Add(time.Second), "a", "again")
+	d.printf(&out, t0.Add(2*time.Second), "a", "again")
+	d.printf(&out, t0.Add(2*time.Second), "b", "other")
+	d.printf(&out, t0.Add(diagRepeatInterval+time.Second), "a", "later")
+
+	want := "clog: first 1\nclog: other\nclog: later (2 similar suppressed)\n"
+	if out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+}
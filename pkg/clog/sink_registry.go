@@ -0,0 +1,111 @@
+// Package clog: registry of sink types usable in Config.Sinks.
+package clog
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// SinkFactory builds a sink from its SinkConfig. Type-specific settings are
+// in SinkConfig.Options. Returning a nil Sink and nil error leaves the sink
+// disabled (as the BetterStack sink does without a token).
+type SinkFactory func(c SinkConfig) (Sink, error)
+
+var (
+	sinkTypesMu sync.RWMutex
+	sinkTypes   = map[string]SinkFactory{
+		"betterstack": func(c SinkConfig) (Sink, error) {
+			s, err := newBetterStackSink(c)
+			if err != nil || s == nil {
+				return nil, err
+			}
+			return s, nil
+		},
+	}
+)
+
+// RegisterSinkType makes name usable as SinkConfig.Type, typically from an
+// init function. It returns an error if name is empty, factory is nil, or
+// the type is already registered (built-in types cannot be replaced).
+func RegisterSinkType(name string, factory SinkFactory) error {
+	if name == "" {
+		return errors.New("sink type name is empty")
+	}
+	if factory == nil {
+		return fmt.Errorf("sink type %q: nil factory", name)
+	}
+	sinkTypesMu.Lock()
+	defer sinkTypesMu.Unlock()
+	if _, ok := sinkTypes[name]; ok {
+		return fmt.Errorf("sink type %q already registered", name)
+	}
+	sinkTypes[name] = factory
+	return nil
+}
+
+// unregisterSinkType removes a type added with RegisterSinkType. It lets
+// tests undo their registrations.
+func unregisterSinkType(name string) {
+	sinkTypesMu.Lock()
+	defer sinkTypesMu.Unlock()
+	delete(sinkTypes, name)
+}
+
+// sinkFactory returns the factory registered for typ.
+func sinkFactory(typ string) (SinkFactory, bool) {
+	sinkTypesMu.RLock()
+	defer sinkTypesMu.RUnlock()
+	f, ok := sinkTypes[typ]
+	return f, ok
+}
+
+// borrowedSink wraps a SinkConfig.Instance. The caller owns the instance:
+// the logger writes and flushes it but never closes it, so the same instance
+// may be passed to Reconfigure again.
+type borrowedSink struct {
+	inner Sink
+}
+
+// Write implements Sink.
+func (s borrowedSink) Write(level Level, iface, formatted string) {
+	s.inner.Write(level, iface, formatted)
+}
+
+// WriteEvent implements EventSink, passing the Event on when the instance
+// takes Events.
+func (s borrowedSink) WriteEvent(e Event) {
+	if es, ok := s.inner.(EventSink); ok {
+		es.WriteEvent(e)
+		return
+	}
+	s.inner.Write(e.Level, e.Iface, flattenEvent(e))
+}
+
+// WriteErr implements ErrorWriter, returning the instance's error when it is
+// an ErrorWriter itself.
+func (s borrowedSink) WriteErr(e Event) error {
+	if ew, ok := s.inner.(ErrorWriter); ok {
+		return ew.WriteErr(e)
+	}
+	s.WriteEvent(e)
+	return nil
+}
+
+// Enabled implements LevelEnabler by asking the instance.
+func (s borrowedSink) Enabled(level Level, iface string) bool {
+	return sinkEnabled(s.inner, level, iface)
+}
+
+// Flush implements Sink.
+func (s borrowedSink) Flush() {
+	s.inner.Flush()
+}
+
+// FlushErr implements ErrorFlusher by asking the instance.
+func (s borrowedSink) FlushErr() error {
+	return flushSink(s.inner)
+}
+
+// Close implements Sink. It does nothing: the caller closes the instance.
+func (s borrowedSink) Close() {}
@@ -0,0 +1,143 @@
+package clog
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// prefixSink is a captureSink built by the "test-prefix" factory.
+type prefixSink struct {
+	captureSink
+	prefix string
+	closed atomic.Bool
+}
+
+func (s *prefixSink) Write(level Level, iface, formatted string) {
+	s.captureSink.Write(level, iface, s.prefix+formatted)
+}
+
+func (s *prefixSink) Close() { s.closed.Store(true) }
+
+func TestRegisterSinkType_BuildsFromOptions(t *testing.T) {
+	var built *prefixSink
+	err := RegisterSinkType("test-prefix", func(c SinkConfig) (Sink, error) {
+		built = &prefixSink{prefix: c.Options["prefix"]}
+		return built, nil
+	})
+	if err != nil {
+		t.Fatalf("RegisterSinkType: %v", err)
+	}
+	t.Cleanup(func() { unregisterSinkType("test-prefix") })
+
+	cfg := DefaultConfig()
+	cfg.Console.Enabled = false
+	cfg.Synchronous = true
+	cfg.Sinks = []SinkConfig{{Type: "test-prefix", Options: map[string]string{"prefix": "> "}}}
+	l, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	l.Info("Test", "hello", String("k", "v"))
+	l.Shutdown(context.Background())
+
+	if got := built.snapshot(); len(got) != 1 || got[0] != "> hello k=v" {
+		t.Errorf("sink got %q, want [\"> hello k=v\"]", got)
+	}
+	if !built.closed.Load() {
+		t.Error("registered sink not closed on Shutdown")
+	}
+	if _, ok := l.Stats().Sinks["test-prefix"]; !ok {
+		t.Errorf("Stats().Sinks = %v, want a test-prefix entry", l.Stats().Sinks)
+	}
+}
+
+func TestRegisterSinkType_Rejects(t *testing.T) {
+	factory := func(SinkConfig) (Sink, error) { return nil, nil }
+	tests := []struct {
+		name    string
+		typ     string
+		factory SinkFactory
+		wantErr string
+	}{
+		{"empty_name", "", factory, "empty"},
+		{"nil_factory", "test-nil", nil, "nil factory"},
+		{"builtin", "betterstack", factory, "already registered"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := RegisterSinkType(tt.typ, tt.factory)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("err = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSinkConfig_InstanceIsNotClosed(t *testing.T) {
+	inst := &prefixSink{}
+	cfg := DefaultConfig()
+	cfg.Console.Enabled = false
+	cfg.Sinks = []SinkConfig{{Name: "capture", Instance: inst}}
+	l, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	l.Warning("Test", "first")
+	if err := l.Reconfigure(cfg); err != nil {
+		t.Fatalf("Reconfigure with the same instance: %v", err)
+	}
+	l.Warning("Test", "second")
+	l.Shutdown(context.Background())
+
+	if got := inst.snapshot(); len(got) != 2 {
+		t.Errorf("instance got %q, want both events", got)
+	}
+	if inst.closed.Load() {
+		t.Error("logger closed a caller-owned instance")
+	}
+	if _, ok := l.Stats().Sinks["capture"]; !ok {
+		t.Errorf("Stats().Sinks = %v, want a capture entry", l.Stats().Sinks)
+	}
+}
+
+func TestSinkConfig_LevelsFilterInstance(t *testing.T) {
+	for _, synchronous := range []bool{false, true} {
+		t.Run(fmt.Sprintf("synchronous=%v", synchronous), func(t *testing.T) {
+			inst := &captureSink{}
+			cfg := panicsConfig(synchronous)
+			cfg.Sinks = []SinkConfig{{Name: "capture", Instance: inst,
+				MinLevel: LevelWarning, OmitLevels: map[Level]bool{LevelError: true}}}
+			l, err := New(cfg)
+			if err != nil {
+				t.Fatalf("New: %v", err)
+			}
+			if l.Enabled(LevelInfo, "T") || l.Enabled(LevelError, "T") || !l.Enabled(LevelWarning, "T") {
+				t.Error("Enabled does not follow the sink's MinLevel and OmitLevels")
+			}
+			l.Info("T", "info")
+			l.Warning("T", "warning")
+			l.Error("T", "error")
+			l.Shutdown(context.Background())
+
+			if got := inst.snapshot(); len(got) != 1 || got[0] != "warning" {
+				t.Errorf("instance got %q, want only warning", got)
+			}
+		})
+	}
+}
+
+func TestSinkConfig_FormatRejectedWhereUnused(t *testing.T) {
+	cfg := panicsConfig(true)
+	cfg.Sinks = []SinkConfig{{Name: "capture", Instance: &captureSink{}, Format: "json"}}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "Sinks[0].Format") {
+		t.Errorf("Validate = %v, want a Sinks[0].Format error", err)
+	}
+	cfg.Sinks = []SinkConfig{{Type: "betterstack", Format: "json"}}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate with a betterstack Format: %v", err)
+	}
+}
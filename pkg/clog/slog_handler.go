@@ -0,0 +1,216 @@
+// Package clog: log/slog Handler backed by a clog Logger.
+package clog
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"time"
+)
+
+// slog levels for the clog levels that have no slog equivalent. They sit
+// between the standard slog levels so ordering is preserved:
+// Debug(-4) < Info(0) < Success(2) < Warn(4) < Fail(6) < Error(8) < Catastrophe(12).
+const (
+	SlogLevelSuccess     = slog.Level(2)
+	SlogLevelFail        = slog.Level(6)
+	SlogLevelCatastrophe = slog.Level(12)
+)
+
+// LevelFromSlog maps a slog level onto a clog Level. Levels between two
+// anchors round down, so any custom slog level maps somewhere sensible.
+func LevelFromSlog(l slog.Level) Level {
+	switch {
+	case l < slog.LevelInfo:
+		return LevelDebug
+	case l < SlogLevelSuccess:
+		return LevelInfo
+	case l < slog.LevelWarn:
+		return LevelSuccess
+	case l < SlogLevelFail:
+		return LevelWarning
+	case l < slog.LevelError:
+		return LevelFail
+	case l < SlogLevelCatastrophe:
+		return LevelError
+	default:
+		return LevelCatastrophe
+	}
+}
+
+// SlogLevel maps a clog Level onto the slog level LevelFromSlog maps back
+// from it.
+func SlogLevel(l Level) slog.Level {
+	switch l {
+	case LevelDebug:
+		return slog.LevelDebug
+	case LevelInfo:
+		return slog.LevelInfo
+	case LevelSuccess:
+		return SlogLevelSuccess
+	case LevelWarning:
+		return slog.LevelWarn
+	case LevelFail:
+		return SlogLevelFail
+	case LevelError:
+		return slog.LevelError
+	default:
+		return SlogLevelCatastrophe
+	}
+}
+
+// SlogHandlerOptions configures NewSlogHandler.
+type SlogHandlerOptions struct {
+	// Iface is the facility records are logged under. It is joined to the
+	// Logger's bound name (see Logger.Named); empty means the bound name, or
+	// "Application" when there is none.
+	Iface string
+	// Level is the minimum slog level passed to the logger. Nil means all
+	// levels (filtering is then left to the sinks).
+	Level slog.Leveler
+}
+
+// slogHandler implements slog.Handler on top of a Logger. Records are turned
+// into Events and enqueued through the Logger's agent, so dedupe, redaction,
+// hooks, sinks and drop accounting apply exactly as for clog calls.
+type slogHandler struct {
+	logger *Logger
+	opts   SlogHandlerOptions
+	fields []Field // attrs from WithAttrs, already group-qualified
+	group  string  // current group prefix, e.g. "http.req."
+}
+
+// NewSlogHandler returns a slog.Handler that logs through l. Attributes become
+// structured fields; groups qualify keys with dots ("http.method"). The
+// Logger's context extractors run on the context passed to slog's *Context
+// methods. opts may be nil.
+//
+//	slog.SetDefault(slog.New(clog.NewSlogHandler(clog.Default(), nil)))
+func NewSlogHandler(l *Logger, opts *SlogHandlerOptions) slog.Handler {
+	h := &slogHandler{logger: l}
+	if opts != nil {
+		h.opts = *opts
+	}
+	return h
+}
+
+// Enabled implements slog.Handler.
+func (h *slogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	if h.logger == nil {
+		return false
+	}
+	if h.opts.Level != nil && level < h.opts.Level.Level() {
+		return false
+	}
+	return h.logger.agent.levelEnabled(h.logger.facility(h.opts.Iface), LevelFromSlog(level))
+}
+
+// Handle implements slog.Handler.
+func (h *slogHandler) Handle(ctx context.Context, r slog.Record) error {
+	l := h.logger
+	if l == nil {
+		return nil
+	}
+	iface := l.facility(h.opts.Iface)
+	level := LevelFromSlog(r.Level)
+	if !l.agent.levelEnabled(iface, level) {
+		return nil
+	}
+	// Bound fields and context correlation fields, then handler and record attrs.
+	base := l.contextFields(ctx)
+	fields := make([]Field, 0, len(base)+len(h.fields)+r.NumAttrs())
+	fields = append(fields, base...)
+	fields = append(fields, h.fields...)
+	r.Attrs(func(a slog.Attr) bool {
+		fields = appendAttr(fields, h.group, a)
+		return true
+	})
+
+	// Message is passed without Params so it is never treated as a format string.
+	// The record's own time is the call-site time; submit fills it in when zero.
+	e := Event{
+		Level:   level,
+		Iface:   iface,
+		Message: r.Message,
+		Fields:  fields,
+		Time:    r.Time,
+	}
+	// slog already captured the call site; Caller.Skip does not apply here.
+	if c := l.agent.cfg.Load().Caller; c.Enabled && level.AtLeast(c.MinLevel) {
+		e.pc = r.PC
+	}
+	l.submit(e)
+	return nil
+}
+
+// WithAttrs implements slog.Handler.
+func (h *slogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	child := *h
+	child.fields = h.fields[:len(h.fields):len(h.fields)]
+	for _, a := range attrs {
+		child.fields = appendAttr(child.fields, h.group, a)
+	}
+	return &child
+}
+
+// WithGroup implements slog.Handler.
+func (h *slogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	child := *h
+	child.group = h.group + name + "."
+	return &child
+}
+
+// appendAttr converts a slog attribute into fields, flattening groups into
+// dotted keys. Empty attributes and empty groups are dropped, as slog requires.
+func appendAttr(fields []Field, prefix string, a slog.Attr) []Field {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return fields
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		attrs := a.Value.Group()
+		if len(attrs) == 0 {
+			return fields
+		}
+		groupPrefix := prefix
+		if a.Key != "" {
+			groupPrefix = prefix + a.Key + "."
+		}
+		for _, ga := range attrs {
+			fields = appendAttr(fields, groupPrefix, ga)
+		}
+		return fields
+	}
+	return append(fields, fieldFromSlog(prefix+a.Key, a.Value))
+}
+
+// fieldFromSlog converts a resolved, non-group slog value into a Field.
+func fieldFromSlog(key string, v slog.Value) Field {
+	switch v.Kind() {
+	case slog.KindString:
+		return String(key, v.String())
+	case slog.KindInt64:
+		return Int64(key, v.Int64())
+	case slog.KindUint64:
+		if u := v.Uint64(); u <= math.MaxInt64 {
+			return Int64(key, int64(u))
+		}
+		return Field{Key: key, Kind: FieldAny, any: v.Uint64()}
+	case slog.KindFloat64:
+		return Float64(key, v.Float64())
+	case slog.KindBool:
+		return Bool(key, v.Bool())
+	case slog.KindDuration:
+		return Dur(key, v.Duration())
+	case slog.KindTime:
+		return String(key, v.Time().Format(time.RFC3339Nano))
+	default:
+		return Any(key, v.Any())
+	}
+}
@@ -0,0 +1,154 @@
+package clog
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLevelFromSlog_RoundTrip(t *testing.T) {
+	for l := LevelDebug; l <= LevelCatastrophe; l++ {
+		if got := LevelFromSlog(SlogLevel(l)); got != l {
+			t.Errorf("LevelFromSlog(SlogLevel(%s)) = %s", l, got)
+		}
+	}
+	tests := []struct {
+		in   slog.Level
+		want Level
+	}{
+		{slog.LevelDebug - 4, LevelDebug},
+		{slog.LevelInfo + 1, LevelInfo},
+		{slog.LevelWarn + 1, LevelWarning},
+		{slog.LevelError + 2, LevelError},
+		{slog.Level(100), LevelCatastrophe},
+	}
+	for _, tt := range tests {
+		if got := LevelFromSlog(tt.in); got != tt.want {
+			t.Errorf("LevelFromSlog(%v) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+// newSlogTestLogger returns a Logger whose events are captured by the hook.
+func newSlogTestLogger(t *testing.T) (*Logger, *testHook) {
+	t.Helper()
+	hook := &testHook{}
+	cfg := DefaultConfig()
+	cfg.Console.Enabled = false
+	cfg.Dedupe.Enabled = false
+	cfg.Hooks.Global = []Hook{hook}
+	l, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return l, hook
+}
+
+// fieldMap flattens an event's fields into key -> rendered value.
+func fieldMap(e Event) map[string]string {
+	m := make(map[string]string, len(e.Fields))
+	for _, f := range e.Fields {
+		m[f.Key] = f.String()
+	}
+	return m
+}
+
+func TestSlogHandler_AttrsAndGroups(t *testing.T) {
+	l, hook := newSlogTestLogger(t)
+	logger := slog.New(NewSlogHandler(l.Named("HTTP"), nil))
+
+	logger.With("svc", "api").WithGroup("req").With("method", "GET").Info(
+		"handled 100%s",
+		"status", 200,
+		slog.Group("timing", slog.Duration("total", 3*time.Millisecond)),
+		slog.Group("empty"),
+		slog.Attr{},
+		"err", errors.New("peer alice@example.com reset"),
+	)
+	logger.Log(context.Background(), SlogLevelCatastrophe, "meltdown")
+
+	l.Shutdown(context.Background())
+	events := hook.getEvents()
+	if len(events) != 2 {
+		t.Fatalf("got %d events, want 2", len(events))
+	}
+
+	e := events[0]
+	if e.Level != LevelInfo || e.Iface != "HTTP" {
+		t.Errorf("level/iface = %s/%q, want INFO/HTTP", e.Level, e.Iface)
+	}
+	if e.Message != "handled 100%s" {
+		t.Errorf("Message = %q, slog messages must not be treated as format strings", e.Message)
+	}
+	want := map[string]string{
+		"svc":              "api",
+		"req.method":       "GET",
+		"req.status":       "200",
+		"req.timing.total": "3ms",
+		"req.err":          "peer <email> reset",
+	}
+	got := fieldMap(e)
+	if len(got) != len(want) {
+		t.Errorf("fields = %v, want %v", got, want)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("field %s = %q, want %q", k, got[k], v)
+		}
+	}
+
+	if events[1].Level != LevelCatastrophe {
+		t.Errorf("custom slog level mapped to %s, want CATASTROPHE", events[1].Level)
+	}
+}
+
+func TestSlogHandler_Enabled(t *testing.T) {
+	l, hook := newSlogTestLogger(t)
+	logger := slog.New(NewSlogHandler(l, &SlogHandlerOptions{Iface: "Lib", Level: slog.LevelWarn}))
+
+	logger.Debug("dropped")
+	logger.Info("dropped")
+	logger.Warn("kept")
+
+	l.Shutdown(context.Background())
+	events := hook.getEvents()
+	if len(events) != 1 || events[0].Level != LevelWarning || events[0].Iface != "Lib" {
+		t.Errorf("events = %+v, want one WARNING from Lib", events)
+	}
+	if got := l.Stats().AcceptedCount; got != 1 {
+		t.Errorf("AcceptedCount = %d, want 1 (filtered records never enqueue)", got)
+	}
+
+	if NewSlogHandler(nil, nil).Enabled(context.Background(), slog.LevelError) {
+		t.Error("handler over a nil Logger should report disabled")
+	}
+}
+
+func TestSlogHandler_DropAccounting(t *testing.T) {
+	l, _ := newSlogTestLogger(t)
+	l.Shutdown(context.Background())
+
+	slog.New(NewSlogHandler(l, nil)).Error("after shutdown")
+	if got := l.Stats().DropsPerLevel[LevelError]; got != 1 {
+		t.Errorf("DropsPerLevel[Error] = %d, want 1", got)
+	}
+}
+
+func TestSlogHandler_WithAttrsDoesNotAlias(t *testing.T) {
+	base := NewSlogHandler(nil, nil).WithAttrs([]slog.Attr{slog.String("a", "1")}).(*slogHandler)
+	x := base.WithAttrs([]slog.Attr{slog.String("x", "1")}).(*slogHandler)
+	y := base.WithAttrs([]slog.Attr{slog.String("y", "1")}).(*slogHandler)
+	keys := func(h *slogHandler) string {
+		var parts []string
+		for _, f := range h.fields {
+			parts = append(parts, f.Key)
+		}
+		return strings.Join(parts, ",")
+	}
+	if keys(x) != "a,x" || keys(y) != "a,y" || keys(base) != "a" {
+		t.Errorf("WithAttrs aliasing: base=%s x=%s y=%s", keys(base), keys(x), keys(y))
+	}
+}
@@ -1,59 +1,209 @@
 // Package clog: statistics tracking.
 package clog
 
-import "sync/atomic"
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
 
-// Stats holds logging statistics.
+// Stats holds logging statistics. DropsPerLevel counts events dropped
+// immediately because the queue was full (or the logger was shut down), and
+// under "drop_old" the queued events evicted to make room, by their level;
+// TimeoutDropsPerLevel counts events dropped by the "block" policy after
+// waiting BlockTimeout for space; ShedPerLevel counts events refused by the
+// ShedThresholds ladder before the queue was full.
 type Stats struct {
-	DropsPerLevel map[Level]int64
-	AcceptedCount int64
-	EmittedCount  int64
+	DropsPerLevel        map[Level]int64
+	TimeoutDropsPerLevel map[Level]int64
+	ShedPerLevel         map[Level]int64
+	AcceptedCount        int64
+	EmittedCount         int64
+	// LazyPanics is the number of Lazy params whose function panicked; the
+	// event was written with a %!v(PANIC=...) placeholder in its place.
+	LazyPanics int64
+	// Sinks holds per-sink delivery counters keyed by sink name ("console",
+	// "file", SinkConfig.Name or Type).
+	Sinks map[string]SinkStats
+	// Hooks holds per-hook panic counters keyed by position in Config.Hooks
+	// ("Hooks.Global[0]", "Hooks.PerLevel[ERROR][1]"), and those of
+	// Config.OnSinkError under "OnSinkError".
+	Hooks map[string]HookStats
 }
 
-// stats holds the global statistics.
-var globalStats = struct {
-	dropsPerLevel map[Level]*int64
-	accepted      int64
-	emitted       int64
-}{
-	dropsPerLevel: make(map[Level]*int64),
+// HookStats holds panic counters for one hook.
+type HookStats struct {
+	// Panics is the number of OnLog calls that panicked.
+	Panics int64
+	// Quarantined is set once the hook was disabled after
+	// MaxConsecutivePanics panics in a row.
+	Quarantined bool
 }
 
-func init() {
-	// Initialize atomic counters for each level
-	for l := LevelDebug; l <= LevelCatastrophe; l++ {
-		var v int64
-		globalStats.dropsPerLevel[l] = &v
-	}
+// SinkStats holds delivery counters for one sink.
+type SinkStats struct {
+	// Written is the number of events handed to the sink by its worker.
+	Written int64
+	// Dropped is the number of events lost because the sink's buffer was
+	// full (per its DeliveryConfig.DropPolicy) or the sink was closed.
+	Dropped int64
+	// SlowWrites is the number of writes that took longer than SlowWriteThreshold.
+	SlowWrites int64
+	// Errors is the number of writes, flushes and closes that failed (see
+	// ErrorWriter, ErrorFlusher, ErrorCloser and BatchSink).
+	Errors int64
+	// LastError is the most recent error as SinkError text ("" if none), and
+	// LastErrorAt when it happened.
+	LastError   string
+	LastErrorAt time.Time
+	// Panics is the number of calls into the sink that panicked; each also
+	// counts as an error. Quarantined is set once the sink was disabled after
+	// MaxConsecutivePanics panics in a row.
+	Panics      int64
+	Quarantined bool
+}
+
+// sinkCounters holds the live counters behind one SinkStats.
+type sinkCounters struct {
+	written    atomic.Int64
+	dropped    atomic.Int64
+	slowWrites atomic.Int64
+	errors     atomic.Int64
+	lastError  atomic.Pointer[lastSinkError]
+	panics     atomic.Int64
+	// quarantined is reset when a sink with this name is started.
+	quarantined atomic.Bool
+}
+
+// hookCounters holds the live counters behind one HookStats.
+type hookCounters struct {
+	panics atomic.Int64
+	// quarantined is reset when Hooks are (re)configured.
+	quarantined atomic.Bool
+}
+
+// statsState holds the live counters for one logger. Every agent owns one; the
+// default logger created by Init shares globalStats so GetStats keeps counting
+// across Shutdown/Init cycles.
+type statsState struct {
+	dropsPerLevel        [LevelCatastrophe + 1]atomic.Int64
+	timeoutDropsPerLevel [LevelCatastrophe + 1]atomic.Int64
+	shedPerLevel         [LevelCatastrophe + 1]atomic.Int64
+	accepted             atomic.Int64
+	emitted              atomic.Int64
+	lazyPanics           atomic.Int64
+
+	sinksMu sync.Mutex
+	sinks   map[string]*sinkCounters
+	hooks   map[string]*hookCounters // guarded by sinksMu
 }
 
+// globalStats holds the statistics reported by GetStats.
+var globalStats statsState
+
 // recordAccepted increments the accepted count.
-func recordAccepted() {
-	atomic.AddInt64(&globalStats.accepted, 1)
+func (s *statsState) recordAccepted() {
+	s.accepted.Add(1)
 }
 
 // recordEmitted increments the emitted count.
-func recordEmitted() {
-	atomic.AddInt64(&globalStats.emitted, 1)
+func (s *statsState) recordEmitted() {
+	s.emitted.Add(1)
 }
 
 // recordDrop increments the drop count for a level.
-func recordDrop(level Level) {
-	if counter, ok := globalStats.dropsPerLevel[level]; ok {
-		atomic.AddInt64(counter, 1)
+func (s *statsState) recordDrop(level Level) {
+	if level >= LevelDebug && level <= LevelCatastrophe {
+		s.dropsPerLevel[level].Add(1)
 	}
 }
 
-// GetStats returns current statistics.
-func GetStats() Stats {
+// recordTimeoutDrop increments the blocked-then-dropped count for a level.
+func (s *statsState) recordTimeoutDrop(level Level) {
+	if level >= LevelDebug && level <= LevelCatastrophe {
+		s.timeoutDropsPerLevel[level].Add(1)
+	}
+}
+
+// recordShed increments the load-shed count for a level.
+func (s *statsState) recordShed(level Level) {
+	if level >= LevelDebug && level <= LevelCatastrophe {
+		s.shedPerLevel[level].Add(1)
+	}
+}
+
+// sink returns the counters for the named sink, creating them on first use.
+// Counters persist by name, so a sink keeps counting across Init cycles of
+// the default logger.
+func (s *statsState) sink(name string) *sinkCounters {
+	s.sinksMu.Lock()
+	defer s.sinksMu.Unlock()
+	if s.sinks == nil {
+		s.sinks = make(map[string]*sinkCounters)
+	}
+	c, ok := s.sinks[name]
+	if !ok {
+		c = &sinkCounters{}
+		s.sinks[name] = c
+	}
+	return c
+}
+
+// hook returns the counters for the named hook, creating them on first use.
+func (s *statsState) hook(name string) *hookCounters {
+	s.sinksMu.Lock()
+	defer s.sinksMu.Unlock()
+	if s.hooks == nil {
+		s.hooks = make(map[string]*hookCounters)
+	}
+	c, ok := s.hooks[name]
+	if !ok {
+		c = &hookCounters{}
+		s.hooks[name] = c
+	}
+	return c
+}
+
+// snapshot returns a point-in-time copy of the counters.
+func (s *statsState) snapshot() Stats {
 	stats := Stats{
-		DropsPerLevel: make(map[Level]int64),
-		AcceptedCount: atomic.LoadInt64(&globalStats.accepted),
-		EmittedCount:  atomic.LoadInt64(&globalStats.emitted),
+		DropsPerLevel:        make(map[Level]int64),
+		TimeoutDropsPerLevel: make(map[Level]int64),
+		ShedPerLevel:         make(map[Level]int64),
+		AcceptedCount:        s.accepted.Load(),
+		EmittedCount:         s.emitted.Load(),
+		LazyPanics:           s.lazyPanics.Load(),
+	}
+	for level := LevelDebug; level <= LevelCatastrophe; level++ {
+		stats.DropsPerLevel[level] = s.dropsPerLevel[level].Load()
+		stats.TimeoutDropsPerLevel[level] = s.timeoutDropsPerLevel[level].Load()
+		stats.ShedPerLevel[level] = s.shedPerLevel[level].Load()
 	}
-	for level, counter := range globalStats.dropsPerLevel {
-		stats.DropsPerLevel[level] = atomic.LoadInt64(counter)
+	s.sinksMu.Lock()
+	stats.Sinks = make(map[string]SinkStats, len(s.sinks))
+	for name, c := range s.sinks {
+		ss := SinkStats{
+			Written:    c.written.Load(),
+			Dropped:    c.dropped.Load(),
+			SlowWrites: c.slowWrites.Load(),
+			Errors:     c.errors.Load(),
+			Panics:     c.panics.Load(),
+		}
+		ss.Quarantined = c.quarantined.Load()
+		if last := c.lastError.Load(); last != nil {
+			ss.LastError, ss.LastErrorAt = last.msg, last.at
+		}
+		stats.Sinks[name] = ss
 	}
+	stats.Hooks = make(map[string]HookStats, len(s.hooks))
+	for name, c := range s.hooks {
+		stats.Hooks[name] = HookStats{Panics: c.panics.Load(), Quarantined: c.quarantined.Load()}
+	}
+	s.sinksMu.Unlock()
 	return stats
 }
 
+// GetStats returns current statistics for the package-level logger.
+func GetStats() Stats {
+	return globalStats.snapshot()
+}
@@ -0,0 +1,117 @@
+// Package clog: synchronous mode for tests and short-lived programs.
+package clog
+
+// syncSink is the Config.Synchronous counterpart of asyncSink: it writes on
+// the logging goroutine, with no buffer, and keeps the same per-sink stats.
+type syncSink struct {
+	name  string
+	inner Sink
+	line  lineSink  // inner, if it is one and not a BatchSink
+	batch BatchSink // inner, if it is one
+	one   [1]Event  // the batch of one handed to batch
+	cfg   DeliveryConfig
+	stats *sinkCounters
+	guard sinkGuard
+
+	levels sinkLevels  // set by startSinks before the sink is used
+	spec   interface{} // likewise; see keepRunning
+}
+
+// newSyncSink wraps inner. cfg must be validated; only its
+// SlowWriteThreshold is used, to count slow writes. Errors and panics are handled as by
+// newAsyncSink.
+func newSyncSink(name string, inner Sink, cfg DeliveryConfig, stats *sinkCounters, sup *sinkSupervisor) *syncSink {
+	line, batch := deliveryKind(inner)
+	return &syncSink{name: name, inner: inner, line: line, batch: batch,
+		cfg: cfg.withDefaults(), stats: stats, guard: newSinkGuard(name, stats, sup)}
+}
+
+// Write implements Sink.
+func (s *syncSink) Write(level Level, iface, formatted string) {
+	s.WriteEvent(Event{Level: level, Iface: iface, Message: formatted})
+}
+
+// WriteEvent implements EventSink.
+func (s *syncSink) WriteEvent(e Event) {
+	if !s.levels.allow(e.Level) {
+		return
+	}
+	if s.guard.quarantined.Load() {
+		s.stats.dropped.Add(1)
+		return
+	}
+	if s.batch != nil {
+		// Sinks are only written under the logger's syncMu.
+		s.one[0] = e
+		_ = s.guard.call("write", func() error {
+			return writeBatchCounted(s.batch, s.one[:], s.cfg.SlowWriteThreshold, s.stats)
+		})
+		s.one[0] = Event{}
+		return
+	}
+	_ = s.guard.call("write", func() error {
+		return writeCounted(s.inner, e, s.cfg.SlowWriteThreshold, s.stats)
+	})
+}
+
+// lineFormat reports the format of the wrapped sink if it is a lineSink.
+func (s *syncSink) lineFormat() (lineFormat, bool) {
+	if s.line == nil {
+		return 0, false
+	}
+	return s.line.lineFormat(), true
+}
+
+// writeRendered writes an event rendered in r to the wrapped lineSink.
+func (s *syncSink) writeRendered(e Event, r *rendering) {
+	if !s.levels.allow(e.Level) {
+		return
+	}
+	if s.guard.quarantined.Load() {
+		s.stats.dropped.Add(1)
+		return
+	}
+	_ = s.guard.call("write", func() error {
+		return writeLineCounted(s.line, e, r, s.cfg.SlowWriteThreshold, s.stats)
+	})
+}
+
+// Enabled implements LevelEnabler by applying the sink's levels and asking
+// the wrapped sink; a quarantined sink is enabled for nothing.
+func (s *syncSink) Enabled(level Level, iface string) bool {
+	return s.levels.allow(level) && s.guard.enabled(s.inner, level, iface)
+}
+
+// Flush implements Sink.
+func (s *syncSink) Flush() {
+	_ = s.flush()
+}
+
+// flush flushes the wrapped sink and reports a failure.
+func (s *syncSink) flush() error {
+	if s.guard.quarantined.Load() {
+		return errQuarantined
+	}
+	return s.guard.call("flush", func() error { return flushSink(s.inner) })
+}
+
+// Close implements Sink. A quarantined sink is still closed.
+func (s *syncSink) Close() {
+	_ = s.guard.call("close", func() error { return closeSink(s.inner) })
+}
+
+// handleInline runs e (an event or a control request) through the pipeline
+// on the calling goroutine, serialized with every other caller by syncMu. It
+// reports false, without handling e, once the logger has shut down.
+func (a *agent) handleInline(e Event) bool {
+	a.syncMu.Lock()
+	defer a.syncMu.Unlock()
+	if a.closed.Load() {
+		return false
+	}
+	if e.ctl == nil {
+		a.stats.recordAccepted()
+	}
+	a.handle(e)
+	return true
+}
@@ -0,0 +1,124 @@
+package clog
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func synchronousConfig(dir string, hook Hook) Config {
+	cfg := fileOnlyConfig(dir, hook)
+	cfg.Synchronous = true
+	cfg.QueueSize = 0
+	return cfg
+}
+
+func TestSynchronous_WritesBeforeLogReturns(t *testing.T) {
+	dir := t.TempDir()
+	hook := &testHook{}
+	l, err := New(synchronousConfig(dir, hook))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer l.Shutdown(context.Background())
+
+	l.Info("Test", "user %s logged in", "alice@example.com", Int("attempt", 2))
+
+	events := hook.getEvents()
+	if len(events) != 1 {
+		t.Fatalf("hook saw %d events right after the call, want 1", len(events))
+	}
+	if strings.Contains(events[0].Message, "alice@example.com") {
+		t.Errorf("hook saw unredacted message %q", events[0].Message)
+	}
+	got := readLog(t, dir)
+	if !strings.Contains(got, "logged in") || !strings.Contains(got, "attempt=2") {
+		t.Errorf("file = %q, want the event written before Info returned", got)
+	}
+}
+
+func TestSynchronous_StatsMatchAsync(t *testing.T) {
+	run := func(synchronous bool) Stats {
+		cfg := fileOnlyConfig(t.TempDir(), &testHook{})
+		cfg.Synchronous = synchronous
+		cfg.Dedupe.Enabled = true
+		l, err := New(cfg)
+		if err != nil {
+			t.Fatalf("New: %v", err)
+		}
+		for i := 0; i < 3; i++ {
+			l.Info("Test", "repeated")
+		}
+		l.Warning("Test", "different")
+		l.Shutdown(context.Background())
+		l.Info("Test", "after shutdown")
+		return l.Stats()
+	}
+
+	asyncStats, syncStats := run(false), run(true)
+	if syncStats.AcceptedCount != asyncStats.AcceptedCount || syncStats.EmittedCount != asyncStats.EmittedCount {
+		t.Errorf("synchronous accepted/emitted = %d/%d, async %d/%d",
+			syncStats.AcceptedCount, syncStats.EmittedCount, asyncStats.AcceptedCount, asyncStats.EmittedCount)
+	}
+	if syncStats.DropsPerLevel[LevelInfo] != asyncStats.DropsPerLevel[LevelInfo] {
+		t.Errorf("synchronous drops = %v, async %v", syncStats.DropsPerLevel, asyncStats.DropsPerLevel)
+	}
+	if syncStats.Sinks["file"] != asyncStats.Sinks["file"] {
+		t.Errorf("synchronous file stats = %+v, async %+v", syncStats.Sinks["file"], asyncStats.Sinks["file"])
+	}
+}
+
+func TestSynchronous_ConcurrentCallersAreSerialized(t *testing.T) {
+	dir := t.TempDir()
+	l, err := New(synchronousConfig(dir, &testHook{}))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				l.Info("Test", "event %d", i)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := strings.Count(readLog(t, dir), "\n"); n != 400 {
+		t.Errorf("file has %d lines, want 400", n)
+	}
+	l.Shutdown(context.Background())
+}
+
+func TestSynchronous_ReconfigureAndFlushApplyInline(t *testing.T) {
+	dir1, dir2 := t.TempDir(), t.TempDir()
+	l, err := New(synchronousConfig(dir1, &testHook{}))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer l.Shutdown(context.Background())
+
+	l.Info("Test", "before")
+	if err := l.Reconfigure(synchronousConfig(dir2, &testHook{})); err != nil {
+		t.Fatalf("Reconfigure: %v", err)
+	}
+	l.Info("Test", "after")
+	if err := l.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	if got := readLog(t, dir1); !strings.Contains(got, "before") || strings.Contains(got, "after") {
+		t.Errorf("old file = %q", got)
+	}
+	if got := readLog(t, dir2); !strings.Contains(got, "after") {
+		t.Errorf("new file = %q", got)
+	}
+
+	async := fileOnlyConfig(dir2, &testHook{})
+	async.QueueSize = 0
+	if err := l.Reconfigure(async); err == nil {
+		t.Error("Reconfigure switched a synchronous logger to async")
+	}
+}
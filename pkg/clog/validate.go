@@ -0,0 +1,155 @@
+// Package clog: Config validation.
+package clog
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+)
+
+// ConfigError is one invalid Config setting. Field names the setting as a Go
+// selector relative to Config ("QueueSize", "Sinks[1].Type", "File.BaseDir").
+type ConfigError struct {
+	Field string
+	Err   error
+}
+
+// Error implements error.
+func (e *ConfigError) Error() string {
+	return e.Field + ": " + e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *ConfigError) Unwrap() error {
+	return e.Err
+}
+
+// Validate checks the whole Config and reports every problem at once: nil,
+// or the *ConfigError values joined with errors.Join (errors.As finds the
+// first; unwrap the join with Unwrap() []error for all of them). Directories
+// for the file sink and audio writer must exist and be writable, or be
+// creatable under a writable parent. New, InitE and Reconfigure call it.
+func (c Config) Validate() error {
+	var errs []error
+	check := func(field string, err error) {
+		if err != nil {
+			errs = append(errs, &ConfigError{Field: field, Err: err})
+		}
+	}
+
+	if !c.Synchronous && c.QueueSize <= 0 {
+		check("QueueSize", fmt.Errorf("must be positive, got %d", c.QueueSize))
+	}
+	check("DropPolicy", validateDropPolicy(c))
+	if c.PriorityReserve < 0 {
+		check("PriorityReserve", fmt.Errorf("negative priority reserve %d", c.PriorityReserve))
+	}
+	check("ShedThresholds", validateShedding(c))
+	check("FacilityLevels", validateFacilityLevels(c.FacilityLevels))
+	if c.MaxConsecutivePanics < 0 {
+		check("MaxConsecutivePanics", fmt.Errorf("must not be negative, got %d", c.MaxConsecutivePanics))
+	}
+
+	check("Console.Delivery", c.Console.Delivery.validate())
+	if c.File.BaseDir != "" {
+		check("File.BaseDir", checkWritableDir(c.File.BaseDir))
+		check("File.Delivery", c.File.Delivery.validate())
+	}
+	if c.Audio.Enabled {
+		checkAudio(c.Audio, check)
+	}
+	for i, s := range c.Sinks {
+		checkSink(fmt.Sprintf("Sinks[%d]", i), s, check)
+	}
+	if c.Redaction.ReplaceDefaults && len(c.Redaction.Patterns) == 0 {
+		check("Redaction.ReplaceDefaults", errors.New("set without Patterns (use CORALIE_LOG_REDACT to turn redaction off)"))
+	}
+	for i, p := range c.Redaction.Patterns {
+		check(fmt.Sprintf("Redaction.Patterns[%d]", i), checkRedactPattern(p))
+	}
+	return errors.Join(errs...)
+}
+
+// checkAudio validates an enabled AudioConfig.
+func checkAudio(a AudioConfig, check func(string, error)) {
+	if a.SampleRate <= 0 {
+		check("Audio.SampleRate", fmt.Errorf("must be positive, got %d", a.SampleRate))
+	}
+	if a.Channels <= 0 {
+		check("Audio.Channels", fmt.Errorf("must be positive, got %d", a.Channels))
+	}
+	if a.BitsPerSample != 0 && a.BitsPerSample != 16 {
+		check("Audio.BitsPerSample", fmt.Errorf("only 16-bit PCM is supported, got %d", a.BitsPerSample))
+	}
+	if a.OutputDir == "" {
+		check("Audio.OutputDir", errors.New("must be set when audio logging is enabled"))
+	} else {
+		check("Audio.OutputDir", checkWritableDir(a.OutputDir))
+	}
+}
+
+// checkSink validates one SinkConfig; prefix is its selector ("Sinks[0]").
+func checkSink(prefix string, s SinkConfig, check func(string, error)) {
+	_, known := sinkFactory(s.Type)
+	switch {
+	case s.Instance != nil:
+	case !known:
+		check(prefix+".Type", fmt.Errorf("unknown sink type %q", s.Type))
+	case s.Type == "betterstack" && s.Endpoint != "":
+		check(prefix+".Endpoint", checkEndpoint(s.Endpoint))
+	}
+	switch {
+	case s.Format == "":
+	case s.Instance != nil:
+		check(prefix+".Format", errors.New("not supported for an Instance sink"))
+	case known && s.Type != "betterstack":
+		check(prefix+".Format", fmt.Errorf("not supported by sink type %q (use Options)", s.Type))
+	case s.Format != "text" && s.Format != "json":
+		check(prefix+".Format", fmt.Errorf("unknown format %q (want text or json)", s.Format))
+	}
+	if s.Timeout < 0 {
+		check(prefix+".Timeout", fmt.Errorf("negative timeout %v", s.Timeout))
+	}
+	check(prefix+".Delivery", s.Delivery.validate())
+}
+
+// checkEndpoint accepts absolute http and https URLs.
+func checkEndpoint(endpoint string) error {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%q is not an http(s) URL", endpoint)
+	}
+	return nil
+}
+
+// checkWritableDir reports whether dir, or the nearest existing ancestor it
+// would be created under, is a writable directory. Writability is probed by
+// creating and removing a temporary file.
+func checkWritableDir(dir string) error {
+	for d := filepath.Clean(dir); ; d = filepath.Dir(d) {
+		info, err := os.Stat(d)
+		if errors.Is(err, fs.ErrNotExist) && filepath.Dir(d) != d {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("%s is not a directory", d)
+		}
+		f, err := os.CreateTemp(d, ".clog-probe-*")
+		if err != nil {
+			return fmt.Errorf("%s is not writable: %w", d, err)
+		}
+		name := f.Name()
+		_ = f.Close()
+		_ = os.Remove(name)
+		return nil
+	}
+}
@@ -0,0 +1,110 @@
+package clog
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestValidate_DefaultConfigIsValid(t *testing.T) {
+	if err := DefaultConfig().Validate(); err != nil {
+		t.Fatalf("DefaultConfig().Validate() = %v", err)
+	}
+}
+
+func TestValidate_ReportsEveryProblem(t *testing.T) {
+	notDir := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(notDir, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := DefaultConfig()
+	cfg.QueueSize = 0
+	cfg.DropPolicy = "drop_newest"
+	cfg.File.BaseDir = filepath.Join(notDir, "logs")
+	cfg.Audio = AudioConfig{Enabled: true, Channels: 1, OutputDir: t.TempDir()}
+	cfg.Sinks = []SinkConfig{
+		{Type: "betterstak", Token: "t"},
+		{Type: "betterstack", Token: "t", Endpoint: "in.logs.betterstack.com", Format: "xml"},
+	}
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("Validate accepted an invalid config")
+	}
+	var first *ConfigError
+	if !errors.As(err, &first) {
+		t.Fatalf("error %v is not a *ConfigError", err)
+	}
+
+	var fields []string
+	for _, e := range err.(interface{ Unwrap() []error }).Unwrap() {
+		var ce *ConfigError
+		if !errors.As(e, &ce) {
+			t.Fatalf("joined error %v is not a *ConfigError", e)
+		}
+		fields = append(fields, ce.Field)
+	}
+	sort.Strings(fields)
+	want := []string{
+		"Audio.SampleRate",
+		"DropPolicy",
+		"File.BaseDir",
+		"QueueSize",
+		"Sinks[0].Type",
+		"Sinks[1].Endpoint",
+		"Sinks[1].Format",
+	}
+	if strings.Join(fields, ",") != strings.Join(want, ",") {
+		t.Errorf("fields = %v, want %v", fields, want)
+	}
+}
+
+func TestNew_RejectsUnknownSinkType(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Console.Enabled = false
+	cfg.Sinks = []SinkConfig{{Type: "betterstak", Token: "t"}}
+	if _, err := New(cfg); err == nil || !strings.Contains(err.Error(), `unknown sink type "betterstak"`) {
+		t.Errorf("New err = %v, want unknown sink type", err)
+	}
+}
+
+func TestInitE_ReturnsErrorAndAllowsRetry(t *testing.T) {
+	Shutdown(context.Background())
+
+	bad := DefaultConfig()
+	bad.Console.Enabled = false
+	bad.DropPolicy = "sometimes"
+	if err := InitE(bad); err == nil {
+		t.Fatal("InitE accepted an invalid config")
+	}
+	if IsInitialized() {
+		t.Fatal("logger initialized after a failed InitE")
+	}
+
+	good := DefaultConfig()
+	good.Console.Enabled = false
+	if err := InitE(good); err != nil {
+		t.Fatalf("InitE after failure: %v", err)
+	}
+	if !IsInitialized() {
+		t.Error("InitE did not initialize the logger")
+	}
+	Shutdown(context.Background())
+}
+
+func TestInit_PanicsOnInvalidConfig(t *testing.T) {
+	Shutdown(context.Background())
+	defer func() {
+		if recover() == nil {
+			t.Error("Init did not panic")
+		}
+	}()
+	cfg := DefaultConfig()
+	cfg.QueueSize = -1
+	Init(cfg)
+}